@@ -0,0 +1,244 @@
+package q2sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/velmie/qparser"
+)
+
+// These constants are names of the query parameters used for aggregation
+//
+// "?group=status&aggregate=count(*),sum(amount)&having[sum_amount]=gt:100"
+const (
+	GroupParameter     = "group"
+	AggregateParameter = "aggregate"
+	HavingParameter    = "having"
+)
+
+// CountAll is the field name which is used in order to allow "count(*)"
+const CountAll = "*"
+
+// aggregateFunctions maps allowed in the query aggregate functions to SQL functions
+var aggregateFunctions = map[string]string{
+	"count": "COUNT",
+	"sum":   "SUM",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+}
+
+// AllowedAggregates maps a field name to a list of aggregate functions
+// ("count", "sum", "avg", "min", "max") which could be applied to the field.
+// Use CountAll as the field name in order to allow "count(*)"
+type AllowedAggregates map[string][]string
+
+// Aggregation specifies what grouping and aggregation the client is allowed to request.
+//
+// Aggregate results are named as the function followed by the underscore and the field
+// name, e.g. "sum(amount)" is named "sum_amount", "count(*)" is named "count".
+// These names could be used in the "having[...]" filters and in the "sort" parameter
+type Aggregation struct {
+	// GroupFields is a list of fields which could be used in the "group" parameter
+	GroupFields []string
+	// Aggregates specifies aggregate functions which could be used in the "aggregate" parameter
+	Aggregates AllowedAggregates
+	// HavingConditions is a list of conditions which could be used in the "having[...]" filters,
+	// the conditions are created by the ConditionFactory set by the AllowFiltering option
+	HavingConditions []string
+}
+
+// aggregationSets are the lists of the Aggregation as sets, they are computed by the constructor
+type aggregationSets struct {
+	groupFields      map[string]struct{}
+	aggregates       map[string]map[string]struct{}
+	havingConditions map[string]struct{}
+}
+
+func newAggregationSets(a *Aggregation) *aggregationSets {
+	sets := &aggregationSets{
+		groupFields:      make(map[string]struct{}, len(a.GroupFields)),
+		aggregates:       make(map[string]map[string]struct{}, len(a.Aggregates)),
+		havingConditions: make(map[string]struct{}, len(a.HavingConditions)),
+	}
+	fillMapKeys(sets.groupFields, a.GroupFields)
+	fillMapKeys(sets.havingConditions, a.HavingConditions)
+	for field, functions := range a.Aggregates {
+		sets.aggregates[field] = make(map[string]struct{}, len(functions))
+		fillMapKeys(sets.aggregates[field], functions)
+	}
+	return sets
+}
+
+type aggregationRequest struct {
+	columns    []Sqlizer
+	groupBy    []string
+	aggregates map[string]Sqlizer
+	having     []Sqlizer
+}
+
+// retrieveAggregation returns nil if aggregation is not allowed or not requested.
+// The permissions narrow the group and aggregate fields the same way as the selected fields
+func (s *ResourceSelectBuilder) retrieveAggregation(
+	query *qparser.Query,
+	permissions *Permissions,
+) (*aggregationRequest, error) {
+	if s.aggregation == nil {
+		return nil, nil //nolint:nilnil // nil means that aggregation is not requested
+	}
+	group := query.Values.Get(GroupParameter)
+	aggregate := query.Values.Get(AggregateParameter)
+	if group == "" && aggregate == "" {
+		return nil, nil //nolint:nilnil // nil means that aggregation is not requested
+	}
+	r := &aggregationRequest{aggregates: make(map[string]Sqlizer)}
+	if group != "" {
+		groupBy, err := s.retrieveGroup(strings.Split(group, ","), permissions)
+		if err != nil {
+			return nil, err
+		}
+		r.groupBy = groupBy
+		for _, column := range groupBy {
+			r.columns = append(r.columns, RawSQL(column))
+		}
+	}
+	if aggregate != "" {
+		for _, item := range strings.Split(aggregate, ",") {
+			alias, expr, err := s.parseAggregate(item, permissions)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := r.aggregates[alias]; ok {
+				continue
+			}
+			r.aggregates[alias] = expr
+			r.columns = append(r.columns, &Alias{Expr: expr, Name: alias})
+		}
+	}
+	if s.limits != nil {
+		if err := checkLimit(LimitSelectFields, s.limits.MaxSelectFields, len(r.columns), ""); err != nil {
+			return nil, err
+		}
+	}
+	having, err := s.retrieveHaving(query, r.aggregates)
+	if err != nil {
+		return nil, err
+	}
+	r.having = having
+	return r, nil
+}
+
+// retrieveGroup checks and translates the group fields
+func (s *ResourceSelectBuilder) retrieveGroup(fields []string, permissions *Permissions) ([]string, error) {
+	for _, field := range fields {
+		if _, ok := s.aggregationSets.groupFields[field]; !ok {
+			return nil, fmt.Errorf("field %q not allowed for grouping", field)
+		}
+	}
+	groupBy, err := s.translator(fields)
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range groupBy {
+		if !permissions.selectAllowed(column) {
+			return nil, &ForbiddenError{
+				Field:   fields[i],
+				Message: fmt.Sprintf("field %q is forbidden for grouping", fields[i]),
+			}
+		}
+	}
+	return groupBy, nil
+}
+
+// checkSort checks that the sorted column is grouped,
+// the aggregates are sorted by their aliases and do not reach this check
+func (r *aggregationRequest) checkSort(field, column string) error {
+	if column == "" || !containsString(r.groupBy, column) {
+		return fmt.Errorf("field %q cannot be used for sorting since it is neither grouped nor aggregated", field)
+	}
+	return nil
+}
+
+// parseAggregate parses the "function(field)" expression
+func (s *ResourceSelectBuilder) parseAggregate(item string, permissions *Permissions) (alias string, expr Sqlizer, err error) {
+	open := strings.IndexByte(item, '(')
+	if open <= 0 || !strings.HasSuffix(item, ")") {
+		return "", nil, fmt.Errorf("aggregate %q must be in the form function(field)", item)
+	}
+	function, field := strings.ToLower(item[:open]), item[open+1:len(item)-1]
+	sqlFunction, ok := aggregateFunctions[function]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown aggregate function %q", function)
+	}
+	if _, ok = s.aggregationSets.aggregates[field][function]; !ok {
+		return "", nil, fmt.Errorf("aggregate function %q cannot be applied to the field %q", function, field)
+	}
+	if field == CountAll {
+		return function, RawSQL(sqlFunction + "(*)"), nil
+	}
+	column, err := s.translator([]string{field})
+	if err != nil {
+		return "", nil, err
+	}
+	if !permissions.selectAllowed(column[0]) {
+		return "", nil, &ForbiddenError{
+			Field:   field,
+			Message: fmt.Sprintf("field %q is forbidden for aggregation", field),
+		}
+	}
+	return function + "_" + field, RawSQL(sqlFunction + "(" + column[0] + ")"), nil
+}
+
+// retrieveHaving creates conditions from the "having[alias]=condition:args" parameters
+func (s *ResourceSelectBuilder) retrieveHaving(query *qparser.Query, aggregates map[string]Sqlizer) ([]Sqlizer, error) {
+	var having []Sqlizer
+	for _, value := range query.Values[HavingParameter] {
+		if len(value.NestedKeys) != 1 || value.Value == "" {
+			continue
+		}
+		alias := value.NestedKeys[0]
+		expr, ok := aggregates[alias]
+		if !ok {
+			return nil, &FilterError{
+				Field:   alias,
+				Message: fmt.Sprintf("having filters cannot be applied to %q which is not requested aggregate", alias),
+			}
+		}
+		if s.parser == nil || s.conditions == nil {
+			return nil, &FilterError{Field: alias, Message: "having filters are not allowed"}
+		}
+		name, args, err := s.parser.ParseFilterExpression(value.Value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok = s.aggregationSets.havingConditions[name]; !ok {
+			return nil, &FilterError{
+				Filter:  name,
+				Field:   alias,
+				Message: fmt.Sprintf("filter %q cannot be applied to the aggregate %q", name, alias),
+			}
+		}
+		if s.limits != nil {
+			if err = checkLimit(LimitConditionArgs, s.limits.MaxConditionArgs, len(args), alias); err != nil {
+				return nil, err
+			}
+		}
+		condition, err := s.conditions.CreateCondition(name)
+		if err != nil {
+			return nil, err
+		}
+		exprSQL, _, err := expr.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+		cond, err := condition(exprSQL, toInterfaceSlice(args)...)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.limits.checkLikePattern(cond, alias); err != nil {
+			return nil, err
+		}
+		having = append(having, cond)
+	}
+	return having, nil
+}
@@ -0,0 +1,174 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type aggregationTest struct {
+	query     string
+	sql       string
+	args      []interface{}
+	filterErr bool
+	expectErr bool
+}
+
+var aggregationTests = []aggregationTest{
+	{query: "", sql: "SELECT id, status FROM orders"},
+	{
+		query: "group=status&aggregate=count(*),sum(amount)",
+		sql:   "SELECT status, COUNT(*) AS count, SUM(amount) AS sum_amount FROM orders GROUP BY status",
+	},
+	{
+		query: "group=status,customerId&aggregate=avg(amount)",
+		sql:   "SELECT status, customer_id, AVG(amount) AS avg_amount FROM orders GROUP BY status, customer_id",
+	},
+	{
+		query: "aggregate=count(*),max(amount)",
+		sql:   "SELECT COUNT(*) AS count, MAX(amount) AS max_amount FROM orders",
+	},
+	{
+		query: "group=status&aggregate=sum(amount)&filter[status]=eq:paid&having[sum_amount]=gt:100",
+		sql:   "SELECT status, SUM(amount) AS sum_amount FROM orders WHERE status = ? GROUP BY status HAVING SUM(amount) > ?",
+		args:  []interface{}{"paid", "100"},
+	},
+	{
+		query: "group=status&aggregate=sum(amount)&sort=-sum_amount,status",
+		sql:   "SELECT status, SUM(amount) AS sum_amount FROM orders GROUP BY status ORDER BY SUM(amount) DESC, status ASC",
+	},
+	{query: "group=amount", expectErr: true},
+	{query: "group=customerId&aggregate=count(*)&sort=status", expectErr: true},
+	{query: "group=status&aggregate=sum(status)", expectErr: true},
+	{query: "group=status&aggregate=median(amount)", expectErr: true},
+	{query: "group=status&aggregate=count", expectErr: true},
+	{query: "group=status&aggregate=sum(amount)&having[count]=gt:1", filterErr: true},
+	{query: "group=status&aggregate=sum(amount)&having[sum_amount]=eq:1", filterErr: true},
+}
+
+func TestAggregation(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"orders",
+		MapTranslator(map[string]string{
+			"id":         "id",
+			"status":     "status",
+			"amount":     "amount",
+			"customerId": "customer_id",
+		}),
+		WithDefaultFields([]string{"id", "status"}),
+		AllowFiltering(
+			AllowedConditions{"status": []string{filterEq}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		AllowSortingByFields([]string{"status"}),
+		AllowAggregation(Aggregation{
+			GroupFields: []string{"status", "customerId"},
+			Aggregates: AllowedAggregates{
+				CountAll: []string{"count"},
+				"amount": []string{"sum", "avg", "max"},
+			},
+			HavingConditions: []string{"gt"},
+		}),
+	)
+	for _, tt := range aggregationTests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb, err := builder.Build(context.Background(), query)
+		var filterErr *FilterError
+		if errors.As(err, &filterErr) != tt.filterErr {
+			t.Errorf("query %q: unexpected filter error status: got %v", tt.query, err)
+			continue
+		}
+		if (err != nil) != (tt.expectErr || tt.filterErr) {
+			t.Errorf("query %q: unexpected error status: got %v", tt.query, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		sql, args, err := sb.ToSQL()
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		if sql != tt.sql {
+			t.Errorf("query %q:\n\texpected sql %q\n\tgot          %q", tt.query, tt.sql, sql)
+		}
+		if len(tt.args) > 0 && !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("query %q: expected args %+v, got %+v", tt.query, tt.args, args)
+		}
+	}
+}
+
+func TestAggregationNotAllowed(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"orders",
+		MapTranslator(map[string]string{"status": "status"}),
+		WithDefaultFields([]string{"status"}),
+	)
+	query, err := qparser.ParseQuery("group=status&aggregate=count(*)")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb, err := builder.Build(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, _, _ := sb.ToSQL()
+	if sql != "SELECT status FROM orders" {
+		t.Errorf("expected aggregation parameters to be ignored, got %q", sql)
+	}
+}
+
+func TestAggregationPolicyAndLimits(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"orders",
+		MapTranslator(map[string]string{"status": "status", "amount": "amount", "email": "email"}),
+		WithDefaultFields([]string{"status"}),
+		AllowFiltering(AllowedConditions{}, testConditions(), DefaultFilterExpressionParser),
+		AllowAggregation(Aggregation{
+			GroupFields:      []string{"status", "email"},
+			Aggregates:       AllowedAggregates{CountAll: []string{"count"}, "amount": []string{"sum"}},
+			HavingConditions: []string{"gt"},
+		}),
+		WithPolicy(func(context.Context) (*Permissions, error) {
+			return &Permissions{SelectFields: []string{"status"}}, nil
+		}),
+		WithLimits(Limits{MaxSelectFields: 2, MaxConditionArgs: 1}),
+	)
+	tests := []struct {
+		query     string
+		forbidden bool
+		limit     bool
+	}{
+		{query: "group=status&aggregate=count(*)&having[count]=gt:1"},
+		{query: "group=email&aggregate=count(*)", forbidden: true},
+		{query: "group=status&aggregate=sum(amount)", forbidden: true},
+		{query: "group=status&aggregate=count(*),sum(amount)", forbidden: true},
+		{query: "group=status,status&aggregate=count(*)", limit: true},
+		{query: "group=status&aggregate=count(*)&having[count]=gt:1,2", limit: true},
+	}
+	for _, tt := range tests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		_, err = builder.Build(context.Background(), query)
+		var forbidden *ForbiddenError
+		if errors.As(err, &forbidden) != tt.forbidden {
+			t.Errorf("query %q: unexpected forbidden status, got %v", tt.query, err)
+		}
+		var limit *LimitError
+		if errors.As(err, &limit) != tt.limit {
+			t.Errorf("query %q: unexpected limit status, got %v", tt.query, err)
+		}
+		if !tt.forbidden && !tt.limit && err != nil {
+			t.Errorf("query %q: unexpected error %s", tt.query, err)
+		}
+	}
+}
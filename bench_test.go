@@ -0,0 +1,100 @@
+package q2sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+const benchmarkQuery = "fields[articles]=id,title,createdAt&filter[title]=eq:go&filter[id]=any:1,2,3&sort=-createdAt"
+
+func newBenchmarkBuilder(options ...ResourceSelectBuilderOption) *ResourceSelectBuilder {
+	return newArticlesBuilder(append([]ResourceSelectBuilderOption{
+		AllowSelectFields([]string{"id", "title", "created_at"}),
+		AllowSortingByFields([]string{"id", "title", "created_at"}),
+	}, options...)...)
+}
+
+func benchmarkBuild(b *testing.B, builder *ResourceSelectBuilder) {
+	query, err := qparser.ParseQuery(benchmarkQuery)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sb, err := builder.Build(ctx, query)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err = sb.ToSQL(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResourceSelectBuilderBuild(b *testing.B) {
+	benchmarkBuild(b, newBenchmarkBuilder())
+}
+
+func BenchmarkResourceSelectBuilderBuildWithPlanCache(b *testing.B) {
+	benchmarkBuild(b, newBenchmarkBuilder(WithPlanCache(100)))
+}
+
+func newBenchmarkSelectBuilder() *SelectBuilder {
+	return new(SelectBuilder).
+		Select([]string{"id", "title", "created_at"}).
+		From("articles").
+		Where(
+			&Eq{Field: "title", Value: "go"},
+			Or{&In{Field: "id", Values: []interface{}{1, 2, 3}}, IsNull("deleted_at")},
+		).
+		OrderBy(OrderBy{{FieldName: "created_at", Order: qparser.OrderDesc}}).
+		Limit(10)
+}
+
+func BenchmarkSelectBuilderToSQL(b *testing.B) {
+	sb := newBenchmarkSelectBuilder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := sb.ToSQL(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestSelectBuilderToSQLAllocs guards the rendering against allocation regressions
+func TestSelectBuilderToSQLAllocs(t *testing.T) {
+	sb := newBenchmarkSelectBuilder()
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := sb.ToSQL(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	const maxAllocs = 20
+	if allocs > maxAllocs {
+		t.Errorf("expected at most %d allocations, got %.0f", maxAllocs, allocs)
+	}
+}
+
+// TestBuildAllocs guards the Build against allocation regressions
+func TestBuildAllocs(t *testing.T) {
+	builder := newBenchmarkBuilder(WithPlanCache(10))
+	query, err := qparser.ParseQuery(benchmarkQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := builder.Build(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	})
+	const maxAllocs = 40
+	if allocs > maxAllocs {
+		t.Errorf("expected at most %d allocations, got %.0f", maxAllocs, allocs)
+	}
+}
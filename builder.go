@@ -3,6 +3,7 @@ package q2sql
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/velmie/qparser"
 )
@@ -15,6 +16,10 @@ type Builder interface {
 // Extension is used in order to extend a builder
 type Extension func(ctx context.Context, query *qparser.Query, builder *SelectBuilder) error
 
+// SortExpression creates an expression which is used in the "ORDER BY" SQL statement
+// instead of a field, for example distance to the point given in the query
+type SortExpression func(ctx context.Context, query *qparser.Query) (Sqlizer, error)
+
 // AllowedConditions maps a string field name to a list of condition aliases.
 // It is used in order to specify a list of condition which could be applied to the field.
 type AllowedConditions map[string][]string
@@ -27,12 +32,30 @@ type ResourceSelectBuilder struct {
 	allowedSelectFields    map[string]struct{}
 	allowedSelectFieldsSlc []string
 	allowedSortFields      []string
+	allowedSortFieldsSet   map[string]struct{}
+	allowedConditionsSet   map[string]map[string]struct{}
 	translator             Translator
+	reverseTranslator      Translator
 	parser                 FilterExpressionParser
 	conditions             ConditionFactory
 	extensions             []Extension
 	alwaysSelectFields     []string
 	alwaysSelectAllFields  bool
+	argsResolvers          map[string]ArgsResolver
+	relativeTimeFields     map[string]struct{}
+	sortExpressions        map[string]SortExpression
+	policy                 Policy
+	limits                 *Limits
+	ctes                   []CTE
+	aggregation            *Aggregation
+	aggregationSets        *aggregationSets
+	computedFields         map[string]ComputedField
+	dialect                Dialect
+	tracer                 Tracer
+	logger                 Logger
+	plans                  *planCache
+	fieldTypes             map[string]FieldType
+	pageParameters         []PageParameter
 }
 
 // NewResourceSelectBuilder is ResourceSelectBuilder constructor
@@ -55,131 +78,522 @@ func NewResourceSelectBuilder(
 		b.allowedSelectFieldsSlc = b.defaultFields
 	}
 	b.allowedSelectFieldsSlc = removeDuplicateStrings(b.allowedSelectFieldsSlc)
+	b.allowedSortFieldsSet = make(map[string]struct{}, len(b.allowedSortFields))
+	fillMapKeys(b.allowedSortFieldsSet, b.allowedSortFields)
+	b.allowedConditionsSet = make(map[string]map[string]struct{}, len(b.allowedConditions))
+	for field, conditions := range b.allowedConditions {
+		b.allowedConditionsSet[field] = make(map[string]struct{}, len(conditions))
+		fillMapKeys(b.allowedConditionsSet[field], conditions)
+	}
+	if b.aggregation != nil {
+		b.aggregationSets = newAggregationSets(b.aggregation)
+	}
 	return b
 }
 
-// Build builds sql query which depends on the applied options
+// Build builds sql query which depends on the applied options.
+// If the trace is attached to the context (see WithTrace), Build records the steps into it
 func (s *ResourceSelectBuilder) Build(
 	ctx context.Context,
 	query *qparser.Query,
 	sb ...*SelectBuilder,
 ) (*SelectBuilder, error) {
-	var (
-		selectFields []string
-		b            *SelectBuilder
-	)
+	ctx, span := startSpan(ctx, s.tracer, SpanBuild)
+	defer span.End()
+	rendered := new(renderedQuery)
+	b, err := s.build(ctx, query, rendered, sb...)
+	TraceFromContext(ctx).finish(b, err, rendered)
+	if s.tracer != nil || s.logger != nil {
+		observe(ctx, span, s.logger, "q2sql: query built", err, s.buildAttributes(query, b, rendered))
+	}
+	return b, err
+}
+
+// renderedQuery is the SQL of the built query, it is rendered at most once by the Build
+// and shared by the limit check, the trace and the observers
+type renderedQuery struct {
+	done bool
+	sql  string
+	args []interface{}
+	err  error
+}
+
+func (r *renderedQuery) render(b *SelectBuilder) (string, []interface{}, error) {
+	if !r.done {
+		r.sql, r.args, r.err = b.ToSQL()
+		r.done = true
+	}
+	return r.sql, r.args, r.err
+}
+
+func (s *ResourceSelectBuilder) build(
+	ctx context.Context,
+	query *qparser.Query,
+	rendered *renderedQuery,
+	sb ...*SelectBuilder,
+) (*SelectBuilder, error) {
+	var b *SelectBuilder
 	if len(sb) > 0 {
 		b = sb[0]
 	} else {
 		b = new(SelectBuilder)
 	}
-	if s.alwaysSelectAllFields {
-		selectFields = s.allowedSelectFieldsSlc
-	} else {
-		if fields, ok := query.Fields.FieldsByResource(s.resourceName); ok {
-			f, err := s.translator(fields)
-			if err != nil {
-				return nil, err
-			}
-			selectFields = f
-		} else {
-			selectFields = s.defaultFields
-		}
-		selectFields = append(selectFields, s.alwaysSelectFields...)
-		selectFields = removeDuplicateStrings(selectFields)
+	if s.dialect != DialectGeneric {
+		b.Dialect = s.dialect
 	}
-	for _, field := range selectFields {
-		if _, ok := s.allowedSelectFields[field]; !ok {
-			return nil, fmt.Errorf("field %q not allowed for selection criteria", field)
-		}
+	trace := TraceFromContext(ctx)
+	permissions, err := s.resolvePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQueryLimits(query); err != nil {
+		trace.record(TraceStep{Stage: TraceStageLimits}, nil, err)
+		return nil, err
+	}
+	plan := s.planFor(ctx, query)
+	aggregation, err := s.buildColumns(ctx, query, permissions, plan, b)
+	if err != nil {
+		return nil, err
 	}
-	b.Select(selectFields).From(s.resourceName)
-	conditions, err := s.retrieveFilterConditions(query)
+	s.addCTEs(b)
+	conditions, err := s.retrieveFilterConditions(ctx, query, permissions, plan)
 	if err != nil {
 		return nil, err
 	}
 	if len(conditions) > 0 {
 		b.Where(conditions...)
 	}
-	sortList := make([]qparser.Sort, len(query.Sort))
-	sortFields := make([]string, len(query.Sort))
-	for i := 0; i < len(query.Sort); i++ {
-		sortFields[i] = query.Sort[i].FieldName
-	}
-	sortFields, err = s.translator(sortFields)
+	orderBy, err := s.retrieveOrderBy(ctx, query, permissions, aggregation, plan)
 	if err != nil {
 		return nil, err
 	}
-	for i := 0; i < len(query.Sort); i++ {
-		allowed := false
-		for _, allowedName := range s.allowedSortFields {
-			if sortFields[i] == allowedName {
-				allowed = true
-				break
-			}
+	for _, part := range orderBy {
+		b.OrderBy(part)
+	}
+	for _, extension := range s.extensions {
+		err = s.runExtension(ctx, extension, query, b)
+		if trace != nil {
+			trace.record(TraceStep{Stage: TraceStageExtension, Extension: functionName(extension)}, nil, err)
 		}
-		if !allowed {
-			return nil, fmt.Errorf("field %q not allowed for sorting criteria", query.Sort[i].FieldName)
+		if err != nil {
+			return nil, err
 		}
-		sortList[i] = query.Sort[i]
-		sortList[i].FieldName = sortFields[i]
-	}
-	if len(sortList) > 0 {
-		b.OrderBy(OrderBy(sortList))
 	}
-	for _, extension := range s.extensions {
-		if err := extension(ctx, query, b); err != nil {
+	if s.limits != nil && s.limits.MaxBoundParams > 0 {
+		_, args, err := rendered.render(b)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkLimit(LimitBoundParams, s.limits.MaxBoundParams, len(args), ""); err != nil {
+			trace.record(TraceStep{Stage: TraceStageLimits}, nil, err)
 			return nil, err
 		}
 	}
+	s.store(plan)
 	return b, nil
 }
 
-func (s *ResourceSelectBuilder) retrieveFilterConditions(query *qparser.Query) ([]Sqlizer, error) {
+// resolvePermissions returns the permissions of the request, nil means there is no policy
+func (s *ResourceSelectBuilder) resolvePermissions(ctx context.Context) (*Permissions, error) {
+	if s.policy == nil {
+		return nil, nil //nolint:nilnil // no policy means no restrictions
+	}
+	permissions, err := s.policy(ctx)
+	TraceFromContext(ctx).record(TraceStep{Stage: TraceStagePolicy, Check: "permissions resolved"}, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (s *ResourceSelectBuilder) runExtension(
+	ctx context.Context,
+	extension Extension,
+	query *qparser.Query,
+	b *SelectBuilder,
+) error {
+	if s.tracer == nil {
+		return extension(ctx, query, b)
+	}
+	ctx, span := s.tracer.Start(ctx, SpanExtension)
+	defer span.End()
+	err := extension(ctx, query, b)
+	span.SetAttributes(Attribute{Key: AttributeExtension, Value: functionName(extension)})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// buildColumns sets the selected columns and the source, the grouping is set if the aggregation is requested
+func (s *ResourceSelectBuilder) buildColumns(
+	ctx context.Context,
+	query *qparser.Query,
+	permissions *Permissions,
+	plan *planRequest,
+	b *SelectBuilder,
+) (*aggregationRequest, error) {
+	trace := TraceFromContext(ctx)
+	aggregation, err := s.retrieveAggregation(query, permissions)
+	if err != nil || aggregation != nil {
+		step := TraceStep{
+			Stage: TraceStageAggregate,
+			Input: GroupParameter + "=" + query.Values.Get(GroupParameter) + "&" +
+				AggregateParameter + "=" + query.Values.Get(AggregateParameter),
+		}
+		if aggregation != nil {
+			step.Translation = aggregation.groupBy
+			step.Check = "allowed for aggregation"
+			trace.record(step, Columns(aggregation.groupBy), nil)
+		} else {
+			trace.record(step, nil, err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if aggregation != nil {
+		b.Column(columnList(aggregation.columns))
+		b.From(s.resourceName).GroupBy(aggregation.groupBy...)
+		for _, having := range aggregation.having {
+			b.Having(having)
+		}
+		return aggregation, nil
+	}
+	var step TraceStep
+	if trace != nil {
+		step = TraceStep{Stage: TraceStageSelect, Parameter: fieldsParameter + "[" + s.resourceName + "]"}
+		if fields, ok := query.Fields.FieldsByResource(s.resourceName); ok {
+			step.Input = strings.Join(fields, ",")
+			step.Check = "allowed for selection"
+		} else {
+			step.Check = "default fields"
+		}
+	}
+	selectFields, computed, err := s.plannedSelectFields(query, permissions, plan)
+	if err != nil {
+		trace.record(step, nil, err)
+		return nil, err
+	}
+	step.Translation = selectFields
+	trace.record(step, Columns(selectFields), nil)
+	b.From(s.resourceName)
+	if len(computed) == 0 {
+		b.Select(selectFields)
+	} else {
+		b.Column(append(columnList{Columns(selectFields)}, computed...))
+	}
+	return nil, nil //nolint:nilnil // nil means that aggregation is not requested
+}
+
+// plannedSelectFields returns the cached select fields or retrieves them,
+// the fields are cached only if there is no policy since the policy could narrow them.
+// The cached slices are shared by the requests so they are copied on store and on use,
+// changing the columns of the built query must not affect the plan
+func (s *ResourceSelectBuilder) plannedSelectFields(
+	query *qparser.Query,
+	permissions *Permissions,
+	plan *planRequest,
+) ([]string, []Sqlizer, error) {
+	if plan != nil && plan.compiled && plan.plan.selectCached {
+		selectFields := append([]string(nil), plan.plan.selectFields...)
+		var computed []Sqlizer
+		if len(plan.plan.computed) > 0 {
+			computed = append(computed, plan.plan.computed...)
+		}
+		return selectFields, computed, nil
+	}
+	selectFields, computed, err := s.retrieveSelectFields(query, permissions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan != nil && !plan.compiled && s.policy == nil {
+		plan.plan.selectCached = true
+		plan.plan.selectFields = append([]string(nil), selectFields...)
+		if len(computed) > 0 {
+			plan.plan.computed = append([]Sqlizer(nil), computed...)
+		}
+	}
+	return selectFields, computed, nil
+}
+
+// checkQueryLimits checks limits which do not require processing of the query
+func (s *ResourceSelectBuilder) checkQueryLimits(query *qparser.Query) error {
+	if s.limits == nil {
+		return nil
+	}
+	if err := checkLimit(LimitFilters, s.limits.MaxFilters, len(query.Filters), ""); err != nil {
+		return err
+	}
+	if err := checkLimit(LimitSortFields, s.limits.MaxSortFields, len(query.Sort), ""); err != nil {
+		return err
+	}
+	fields, _ := query.Fields.FieldsByResource(s.resourceName)
+	return checkLimit(LimitSelectFields, s.limits.MaxSelectFields, len(fields), "")
+}
+
+func (s *ResourceSelectBuilder) retrieveSelectFields(
+	query *qparser.Query,
+	permissions *Permissions,
+) (selectFields []string, computed []Sqlizer, err error) {
+	if s.alwaysSelectAllFields {
+		selectFields = permissions.filterSelect(s.allowedSelectFieldsSlc)
+	} else {
+		if fields, ok := query.Fields.FieldsByResource(s.resourceName); ok {
+			fields, computed, err = s.retrieveComputedFields(fields, permissions)
+			if err != nil {
+				return nil, nil, err
+			}
+			var f []string
+			f, err = s.translator(fields)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, field := range f {
+				if _, allowed := s.allowedSelectFields[field]; !allowed {
+					return nil, nil, fmt.Errorf("field %q not allowed for selection criteria", fields[i])
+				}
+				if !permissions.selectAllowed(field) {
+					return nil, nil, &ForbiddenError{
+						Field:   fields[i],
+						Message: fmt.Sprintf("field %q is forbidden for selection", fields[i]),
+					}
+				}
+			}
+			selectFields = f
+		} else {
+			selectFields = permissions.filterSelect(s.defaultFields)
+		}
+		selectFields = append(selectFields, permissions.filterSelect(s.alwaysSelectFields)...)
+		selectFields = removeDuplicateStrings(selectFields)
+	}
+	// the requested fields are checked above, these are the default and the always selected fields
+	for _, field := range selectFields {
+		if _, ok := s.allowedSelectFields[field]; !ok {
+			return nil, nil, fmt.Errorf("field %q not allowed for selection criteria", s.clientName(field))
+		}
+	}
+	return selectFields, computed, nil
+}
+
+func (s *ResourceSelectBuilder) retrieveFilterConditions(
+	ctx context.Context,
+	query *qparser.Query,
+	permissions *Permissions,
+	plan *planRequest,
+) ([]Sqlizer, error) {
 	conditions := make([]Sqlizer, 0)
-	for _, filter := range query.Filters {
-		allowList, ok := s.allowedConditions[filter.FieldName]
-		if !ok {
-			return nil, &FilterError{
-				Field:   filter.FieldName,
-				Message: fmt.Sprintf("filters cannot be applied to the field %q", filter.FieldName),
+	var bound []filterValues
+	if plan != nil {
+		bound = make([]filterValues, 0, len(query.Filters))
+	}
+	trace := TraceFromContext(ctx)
+	for i, filter := range query.Filters {
+		var step TraceStep
+		if trace != nil {
+			step = TraceStep{
+				Stage:     TraceStageFilter,
+				Parameter: filterParameter + "[" + filter.FieldName + "]",
+				Input:     filter.Predicate,
 			}
 		}
-		f, err := s.translator([]string{filter.FieldName})
+		cond, values, err := s.filterCondition(ctx, filter, permissions, plan, i, &step)
+		trace.record(step, cond, err)
 		if err != nil {
 			return nil, err
 		}
-		name, args, err := s.parser.ParseFilterExpression(filter.Predicate)
-		if err != nil {
-			return nil, err
+		conditions = append(conditions, cond)
+		if plan != nil {
+			bound = append(bound, values)
 		}
-		allowed := false
-		for _, allowedName := range allowList {
-			if name == allowedName {
-				allowed = true
-				break
-			}
+	}
+	return plan.bindFilters(conditions, bound), nil
+}
+
+// filterCondition creates the condition of the filter and returns the values it is created from,
+// the step is filled for the trace
+func (s *ResourceSelectBuilder) filterCondition(
+	ctx context.Context,
+	filter qparser.Filter,
+	permissions *Permissions,
+	plan *planRequest,
+	i int,
+	step *TraceStep,
+) (Sqlizer, filterValues, error) {
+	if _, ok := s.allowedConditions[filter.FieldName]; !ok {
+		return nil, filterValues{}, &FilterError{
+			Field:   filter.FieldName,
+			Message: fmt.Sprintf("filters cannot be applied to the field %q", filter.FieldName),
+		}
+	}
+	name, args, err := plan.parsedFilter(s, i, filter)
+	if err != nil {
+		return nil, filterValues{}, err
+	}
+	step.Condition = name
+	if s.limits != nil {
+		if err = checkLimit(LimitConditionArgs, s.limits.MaxConditionArgs, len(args), filter.FieldName); err != nil {
+			return nil, filterValues{}, err
+		}
+	}
+	compiled, err := s.compiledFilter(plan, i, filter.FieldName, name)
+	if err != nil {
+		return nil, filterValues{}, err
+	}
+	if step.Stage != "" {
+		step.Translation = []string{compiled.column}
+	}
+	if !permissions.conditionAllowed(filter.FieldName, name) {
+		return nil, filterValues{}, &ForbiddenError{
+			Filter:  name,
+			Field:   filter.FieldName,
+			Message: fmt.Sprintf("filter %q is forbidden for the field %q", name, filter.FieldName),
 		}
-		if !allowed {
-			return nil, &FilterError{
+	}
+	step.Check = "allowed condition"
+
+	values := toInterfaceSlice(args)
+	if resolve, ok := s.argsResolvers[filter.FieldName]; ok {
+		values, err = resolve(ctx, args)
+		if err != nil {
+			return nil, filterValues{}, &FilterError{
 				Filter:  name,
 				Field:   filter.FieldName,
-				Message: fmt.Sprintf("filter %q cannot be applied to the field %q", name, filter.FieldName),
+				Message: fmt.Sprintf("invalid value of the filter %q applied to the field %q: %s", name, filter.FieldName, err),
 			}
 		}
-		condition, err := s.conditions.CreateCondition(name)
+	}
+
+	buckets := unwrapTimeBuckets(values)
+	cond, err := compiled.condition(compiled.column, values...)
+	if err != nil {
+		return nil, filterValues{}, err
+	}
+	if buckets != nil {
+		cond = expandTimeBuckets(cond, buckets)
+	}
+	if err = s.limits.checkLikePattern(cond, filter.FieldName); err != nil {
+		return nil, filterValues{}, err
+	}
+	return cond, filterValues{values: values, bindable: buckets == nil}, nil
+}
+
+// retrieveOrderBy creates "ORDER BY" parts, consecutive sorts by fields are grouped into single OrderBy
+func (s *ResourceSelectBuilder) retrieveOrderBy(
+	ctx context.Context,
+	query *qparser.Query,
+	permissions *Permissions,
+	aggregation *aggregationRequest,
+	plan *planRequest,
+) ([]Sqlizer, error) {
+	var (
+		parts    []Sqlizer
+		sortList OrderBy
+	)
+	trace := TraceFromContext(ctx)
+	for i, sort := range query.Sort {
+		var step TraceStep
+		if trace != nil {
+			step = TraceStep{Stage: TraceStageSort, Parameter: sortParameter, Input: encodeSort(sort)}
+		}
+		expr, column, err := s.sortBy(ctx, query, sort.FieldName, permissions, aggregation, plan, i)
 		if err != nil {
+			trace.record(step, nil, err)
 			return nil, err
 		}
+		if expr != nil {
+			if len(sortList) > 0 {
+				parts = append(parts, sortList)
+				sortList = nil
+			}
+			orderBy := &OrderByExpr{Expr: expr, Order: sort.Order}
+			step.Check = "sorting by the expression"
+			trace.record(step, orderBy, nil)
+			parts = append(parts, orderBy)
+			continue
+		}
+		sort.FieldName = column
+		if trace != nil {
+			step.Translation = []string{column}
+			step.Check = "allowed for sorting"
+			trace.record(step, OrderBy{sort}, nil)
+		}
+		sortList = append(sortList, sort)
+	}
+	if len(sortList) > 0 {
+		parts = append(parts, sortList)
+	}
+	return parts, nil
+}
 
-		cond, err := condition(f[0], toInterfaceSlice(args)...)
-		if err != nil {
-			return nil, err
+// sortBy returns either the expression if the field is the aggregate, the computed field
+// or the sort expression, or the column the field is translated to
+func (s *ResourceSelectBuilder) sortBy(
+	ctx context.Context,
+	query *qparser.Query,
+	field string,
+	permissions *Permissions,
+	aggregation *aggregationRequest,
+	plan *planRequest,
+	i int,
+) (expr Sqlizer, column string, err error) {
+	if aggregation != nil {
+		if aggregate, ok := aggregation.aggregates[field]; ok {
+			return aggregate, "", nil
+		}
+	}
+	compiled, err := s.compiledSort(plan, i, field)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case compiled.expression != nil, compiled.computed != nil:
+		column = field
+	default:
+		column = compiled.column
+	}
+	if !permissions.sortAllowed(column) {
+		return nil, "", &ForbiddenError{
+			Field:   field,
+			Message: fmt.Sprintf("field %q is forbidden for sorting", field),
+		}
+	}
+	if aggregation != nil {
+		if compiled.expression != nil || compiled.computed != nil {
+			column = ""
 		}
+		if err = aggregation.checkSort(field, column); err != nil {
+			return nil, "", err
+		}
+	}
+	if compiled.expression != nil {
+		expr, err = compiled.expression(ctx, query)
+		return expr, "", err
+	}
+	return compiled.computed, column, nil
+}
 
-		conditions = append(conditions, cond)
+// clientName translates the column back to API name if the reverse translator is set,
+// so the errors refer to the names known to the client
+func (s *ResourceSelectBuilder) clientName(column string) string {
+	name, _ := s.apiName(column)
+	return name
+}
+
+// addCTEs adds common table expressions which are not yet added to the builder
+func (s *ResourceSelectBuilder) addCTEs(b *SelectBuilder) {
+	for _, cte := range s.ctes {
+		exists := false
+		for _, added := range b.CTEs {
+			if added.Name == cte.Name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			b.CTEs = append(b.CTEs, cte)
+		}
 	}
-	return conditions, nil
 }
 
 func toInterfaceSlice(s []string) []interface{} {
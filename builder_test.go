@@ -130,6 +130,77 @@ var resourceBuilderTests = []resourceBuilderTest{
 			AlwaysSelectAllFields(true),
 		},
 	},
+	{
+		title: "Sorting by expression between sorting by fields",
+		query: "sort=createdAt,-relevance,-title&term=bitcoin",
+		sql: fmt.Sprintf(
+			"SELECT * FROM %s ORDER BY %s ASC, MATCH(body) AGAINST (?) DESC, %s DESC",
+			resourceName,
+			resourceFieldCreatedAt,
+			resourceFieldTitle,
+		),
+		args: []interface{}{"bitcoin"},
+		additionalOptions: []ResourceSelectBuilderOption{
+			AllowSortingByFields([]string{resourceFieldCreatedAt, resourceFieldTitle}),
+			AllowSortingByExpressions(map[string]SortExpression{
+				"relevance": func(_ context.Context, query *qparser.Query) (Sqlizer, error) {
+					return &RawSQLWithArgs{"MATCH(body) AGAINST (?)", []interface{}{query.Values.Get("term")}}, nil
+				},
+			}),
+		},
+	},
+}
+
+// testConditions creates the condition factory shared by the tests
+func testConditions() ConditionMap {
+	return ConditionMap{
+		filterEq: func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Eq{Field: field, Value: args[0]}, nil
+		},
+		"neq": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Neq{Field: field, Value: args[0]}, nil
+		},
+		"gt": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Gt{Field: field, Value: args[0]}, nil
+		},
+		"ge": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Ge{Field: field, Value: args[0]}, nil
+		},
+		"lt": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Lt{Field: field, Value: args[0]}, nil
+		},
+		"le": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Le{Field: field, Value: args[0]}, nil
+		},
+		filterAny: func(field string, args ...interface{}) (Sqlizer, error) {
+			return &In{Field: field, Values: args}, nil
+		},
+		filterContains: func(field string, args ...interface{}) (Sqlizer, error) {
+			if len(args) == 0 {
+				return RawSQL(""), nil
+			}
+			return &Like{Field: field, Value: "%" + args[0].(string) + "%"}, nil
+		},
+	}
+}
+
+// newArticlesBuilder creates the builder of the articles shared by the tests: the fields are id, title
+// and createdAt, the title is filtered by "eq", the id by "eq" and "any", the articles are sorted by created_at.
+// The options are applied after these ones, so they could override them
+func newArticlesBuilder(options ...ResourceSelectBuilderOption) *ResourceSelectBuilder {
+	return NewResourceSelectBuilder(
+		resourceName,
+		MapTranslator(map[string]string{"id": resourceFieldID, "title": resourceFieldTitle, "createdAt": resourceFieldCreatedAt}),
+		append([]ResourceSelectBuilderOption{
+			WithDefaultFields([]string{resourceFieldID, resourceFieldTitle}),
+			AllowFiltering(
+				AllowedConditions{"title": {filterEq}, "id": {filterEq, filterAny}},
+				testConditions(),
+				DefaultFilterExpressionParser,
+			),
+			AllowSortingByFields([]string{resourceFieldCreatedAt}),
+		}, options...)...,
+	)
 }
 
 func TestNewResourceSelectBuilder(t *testing.T) {
@@ -0,0 +1,209 @@
+package q2sql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetOperator combines results of the select queries
+type SetOperator string
+
+// Supported set operators
+const (
+	SetUnion     SetOperator = "UNION"
+	SetUnionAll  SetOperator = "UNION ALL"
+	SetIntersect SetOperator = "INTERSECT"
+	SetExcept    SetOperator = "EXCEPT"
+)
+
+// CompoundBuilder combines multiple select queries with set operators
+// and applies ORDER BY, LIMIT and OFFSET to the combined result
+//
+//	q2sql.Compound(articles).UnionAll(videos).OrderBy(q2sql.RawSQL("created_at DESC")).Limit(20)
+type CompoundBuilder struct {
+	Parts        []*SelectBuilder
+	Operators    []SetOperator
+	OrderByParts []Sqlizer
+	LimitPart    string
+	OffsetPart   string
+	// Dialect is used in order to render the parts which have own ORDER BY, LIMIT or OFFSET,
+	// the dialect of the first part is used if it is not set
+	Dialect Dialect
+}
+
+// Compound creates compound builder starting with the given query
+func Compound(first *SelectBuilder) *CompoundBuilder {
+	return &CompoundBuilder{Parts: []*SelectBuilder{first}}
+}
+
+// Combine adds the query combined by the operator with the previous ones
+func (c *CompoundBuilder) Combine(operator SetOperator, sb *SelectBuilder) *CompoundBuilder {
+	c.Parts = append(c.Parts, sb)
+	c.Operators = append(c.Operators, operator)
+	return c
+}
+
+func (c *CompoundBuilder) Union(sb *SelectBuilder) *CompoundBuilder {
+	return c.Combine(SetUnion, sb)
+}
+
+func (c *CompoundBuilder) UnionAll(sb *SelectBuilder) *CompoundBuilder {
+	return c.Combine(SetUnionAll, sb)
+}
+
+func (c *CompoundBuilder) Intersect(sb *SelectBuilder) *CompoundBuilder {
+	return c.Combine(SetIntersect, sb)
+}
+
+func (c *CompoundBuilder) Except(sb *SelectBuilder) *CompoundBuilder {
+	return c.Combine(SetExcept, sb)
+}
+
+func (c *CompoundBuilder) OrderBy(clause Sqlizer) *CompoundBuilder {
+	c.OrderByParts = append(c.OrderByParts, clause)
+	return c
+}
+
+func (c *CompoundBuilder) Limit(limit uint64) *CompoundBuilder {
+	c.LimitPart = strconv.FormatUint(limit, 10)
+	return c
+}
+
+func (c *CompoundBuilder) Offset(offset uint64) *CompoundBuilder {
+	c.OffsetPart = strconv.FormatUint(offset, 10)
+	return c
+}
+
+// ToSQL renders the compound query, a part is wrapped in parentheses
+// if it has its own ORDER BY, LIMIT or OFFSET.
+// An error is returned if the parts select different number of columns
+func (c *CompoundBuilder) ToSQL() (string, []interface{}, error) {
+	sql := getBuffer()
+	defer putBuffer(sql)
+	args, err := c.WriteSQL(sql, make([]interface{}, 0))
+	if err != nil {
+		return "", nil, err
+	}
+	return sql.String(), args, nil
+}
+
+// WriteSQL writes the compound query into the buffer, see SQLWriter
+func (c *CompoundBuilder) WriteSQL(sql *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	if len(c.Parts) < 2 {
+		return nil, errors.New("compound query must have at least two parts")
+	}
+	if len(c.Operators) != len(c.Parts)-1 {
+		return nil, errors.New("compound query must have an operator between each two parts")
+	}
+	err := c.validateParts()
+	if err != nil {
+		return nil, err
+	}
+	for i, part := range c.Parts {
+		if i > 0 {
+			sql.WriteString(" ")
+			sql.WriteString(string(c.Operators[i-1]))
+			sql.WriteString(" ")
+		}
+		args, err = c.writePart(sql, args, part)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.OrderByParts) > 0 {
+		sql.WriteString(" ORDER BY ")
+		args, err = appendToSQL(c.OrderByParts, sql, ", ", args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.LimitPart != "" {
+		sql.WriteString(" LIMIT ")
+		sql.WriteString(c.LimitPart)
+	}
+
+	if c.OffsetPart != "" {
+		sql.WriteString(" OFFSET ")
+		sql.WriteString(c.OffsetPart)
+	}
+
+	return args, nil
+}
+
+// writePart writes the part, the part which has own ORDER BY, LIMIT or OFFSET is wrapped in parentheses,
+// SQLite does not allow parentheses so such part is selected from the subquery
+func (c *CompoundBuilder) writePart(sql *bytes.Buffer, args []interface{}, part *SelectBuilder) ([]interface{}, error) {
+	if len(part.OrderByParts) == 0 && part.LimitPart == "" && part.OffsetPart == "" {
+		return part.WriteSQL(sql, args)
+	}
+	dialect := c.Dialect
+	if dialect == DialectGeneric {
+		dialect = c.Parts[0].Dialect
+	}
+	if dialect == DialectSQLite {
+		sql.WriteString("SELECT * FROM ")
+	}
+	return (&SubQuery{Query: part}).WriteSQL(sql, args)
+}
+
+// validateParts checks that the parts could be combined:
+// they select equal number of columns and have neither common table expressions nor locks
+func (c *CompoundBuilder) validateParts() error {
+	expected := -1
+	for i, part := range c.Parts {
+		if part == nil {
+			return fmt.Errorf("compound query part %d is nil", i)
+		}
+		if len(part.CTEs) > 0 {
+			return fmt.Errorf("compound query part %d cannot have common table expressions", i)
+		}
+		if part.LockPart != nil {
+			return fmt.Errorf("compound query part %d cannot have the lock clause", i)
+		}
+		n, ok := countColumns(part.Columns)
+		if !ok {
+			continue
+		}
+		if expected == -1 {
+			expected = n
+			continue
+		}
+		if n != expected {
+			return fmt.Errorf("compound query parts must select equal number of columns, got %d and %d", expected, n)
+		}
+	}
+	return nil
+}
+
+// countColumns counts selected columns, the second return value is false
+// if the number cannot be determined e.g. because of the wildcard
+func countColumns(columns []Sqlizer) (int, bool) {
+	n := 0
+	for _, column := range columns {
+		if nested, ok := column.(columnList); ok {
+			count, known := countColumns(nested)
+			if !known {
+				return 0, false
+			}
+			n += count
+			continue
+		}
+		list, ok := column.(Columns)
+		if !ok {
+			n++
+			continue
+		}
+		for _, c := range list {
+			if c == "*" || strings.HasSuffix(c, ".*") {
+				return 0, false
+			}
+		}
+		n += len(list)
+	}
+	return n, true
+}
@@ -0,0 +1,113 @@
+package q2sql
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type compoundBuilderTest struct {
+	b     *CompoundBuilder
+	query string
+	args  []interface{}
+	err   bool
+}
+
+func feedSelect(table, kind string) *SelectBuilder {
+	return new(SelectBuilder).
+		Select([]string{"id", "title", "created_at", "'" + kind + "' AS kind"}).
+		From(table).
+		Where(&Eq{Field: "status", Value: kind + "_published"})
+}
+
+var compoundBuilderTests = []compoundBuilderTest{
+	{
+		b: Compound(feedSelect("articles", "article")).
+			UnionAll(feedSelect("videos", "video")).
+			OrderBy(RawSQL("created_at DESC")).
+			Limit(20).
+			Offset(40),
+		query: "SELECT id, title, created_at, 'article' AS kind FROM articles WHERE status = ? " +
+			"UNION ALL SELECT id, title, created_at, 'video' AS kind FROM videos WHERE status = ? " +
+			"ORDER BY created_at DESC LIMIT 20 OFFSET 40",
+		args: []interface{}{"article_published", "video_published"},
+	},
+	{
+		b: Compound(new(SelectBuilder).Select([]string{"user_id"}).From("orders").OrderBy(RawSQL("id")).Limit(5)).
+			Intersect(new(SelectBuilder).Select([]string{"user_id"}).From("reviews")).
+			Except(new(SelectBuilder).Select([]string{"id"}).From("banned").Where(&Gt{Field: "until", Value: 1})).
+			Union(new(SelectBuilder).Select([]string{"*"}).From("admins")),
+		query: "(SELECT user_id FROM orders ORDER BY id LIMIT 5) INTERSECT SELECT user_id FROM reviews " +
+			"EXCEPT SELECT id FROM banned WHERE until > ? UNION SELECT * FROM admins",
+		args: []interface{}{1},
+	},
+	{
+		b: &CompoundBuilder{
+			Parts: []*SelectBuilder{
+				new(SelectBuilder).Select([]string{"user_id"}).From("orders").OrderBy(RawSQL("id")).Limit(5),
+				new(SelectBuilder).Select([]string{"user_id"}).From("reviews"),
+			},
+			Operators: []SetOperator{SetUnion},
+			Dialect:   DialectSQLite,
+		},
+		query: "SELECT * FROM (SELECT user_id FROM orders ORDER BY id LIMIT 5) UNION SELECT user_id FROM reviews",
+		args:  []interface{}{},
+	},
+	{
+		b: Compound(&SelectBuilder{Dialect: DialectSQLite, Columns: []Sqlizer{Columns{"id"}}, FromPart: RawSQL("articles")}).
+			Union(new(SelectBuilder).Select([]string{"id"}).From("videos").Limit(1)),
+		query: "SELECT id FROM articles UNION SELECT * FROM (SELECT id FROM videos LIMIT 1)",
+		args:  []interface{}{},
+	},
+	{
+		b: Compound(new(SelectBuilder).Select([]string{"id"}).From("articles")).
+			Union(new(SelectBuilder).With("v", RawSQL("SELECT 1")).Select([]string{"id"}).From("v")),
+		err: true,
+	},
+	{
+		b: Compound(new(SelectBuilder).Select([]string{"id"}).From("articles").Lock(Lock{Strength: ForUpdate})).
+			Union(new(SelectBuilder).Select([]string{"id"}).From("videos")),
+		err: true,
+	},
+	{
+		b: Compound(new(SelectBuilder).Select([]string{"id", "title"}).From("articles")).
+			Union(new(SelectBuilder).Select([]string{"id"}).From("videos")),
+		err: true,
+	},
+	{
+		b:   Compound(new(SelectBuilder).Select([]string{"id"}).From("articles")),
+		err: true,
+	},
+	{
+		b: &CompoundBuilder{Parts: []*SelectBuilder{
+			new(SelectBuilder).Select([]string{"id"}).From("articles"),
+			new(SelectBuilder).Select([]string{"id"}).From("videos"),
+		}},
+		err: true,
+	},
+	{
+		b: Compound(new(SelectBuilder).Select([]string{"id"}).From("articles")).
+			Union(new(SelectBuilder)),
+		err: true,
+	},
+}
+
+func TestCompoundBuilder(t *testing.T) {
+	for i, tt := range compoundBuilderTests {
+		meta := fmt.Sprintf("test %d", i)
+		sql, args, err := tt.b.ToSQL()
+		if (err != nil) != tt.err {
+			t.Errorf("%s: unexpected error status, got %v", meta, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sql != tt.query {
+			t.Errorf("%s: expected query %q\n\tgot %q", meta, tt.query, sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %+v, got %+v", meta, tt.args, args)
+		}
+	}
+}
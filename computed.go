@@ -0,0 +1,100 @@
+package q2sql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ComputedField is a field which is not a column of the resource,
+// but an SQL expression such as a window function or a calculation.
+// The field is selected as "expr AS name", where name is the field name exposed to the client
+type ComputedField struct {
+	// Expr is the expression of the field, it could contain arguments
+	Expr Sqlizer
+	// Sortable allows sorting by the field, the expression is used in the "ORDER BY" clause
+	Sortable bool
+}
+
+// These are window functions which could be used in the Window
+const (
+	RowNumber   RawSQL = "ROW_NUMBER()"
+	Rank        RawSQL = "RANK()"
+	DenseRank   RawSQL = "DENSE_RANK()"
+	PercentRank RawSQL = "PERCENT_RANK()"
+)
+
+// Window is a window function call: FUNC() OVER (PARTITION BY ... ORDER BY ...)
+//
+//	&Window{
+//		Func:        RowNumber,
+//		PartitionBy: []string{"author_id"},
+//		OrderBy:     OrderBy{{FieldName: "created_at", Order: qparser.OrderDesc}},
+//	}
+type Window struct {
+	// Func is a window or an aggregate function, e.g. RowNumber or RawSQL("SUM(amount)")
+	Func        Sqlizer
+	PartitionBy []string
+	OrderBy     OrderBy
+}
+
+func (w *Window) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(w)
+}
+
+func (w *Window) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	if w.Func == nil {
+		return nil, Error("window function is not specified")
+	}
+	args, err := WriteSQL(buf, args, w.Func)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(" OVER (")
+	if len(w.PartitionBy) > 0 {
+		buf.WriteString("PARTITION BY ")
+		buf.WriteString(strings.Join(w.PartitionBy, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		if len(w.PartitionBy) > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString("ORDER BY ")
+		args, err = w.OrderBy.WriteSQL(buf, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte(')')
+	return args, nil
+}
+
+// retrieveComputedFields separates the computed fields from the requested fields
+func (s *ResourceSelectBuilder) retrieveComputedFields(
+	fields []string,
+	permissions *Permissions,
+) (rest []string, computed []Sqlizer, err error) {
+	if len(s.computedFields) == 0 {
+		return fields, nil, nil
+	}
+	seen := make(map[string]struct{})
+	for _, field := range fields {
+		computedField, ok := s.computedFields[field]
+		if !ok {
+			rest = append(rest, field)
+			continue
+		}
+		if !permissions.selectAllowed(field) {
+			return nil, nil, &ForbiddenError{
+				Field:   field,
+				Message: fmt.Sprintf("field %q is forbidden for selection", field),
+			}
+		}
+		if _, ok = seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		computed = append(computed, &Alias{Expr: computedField.Expr, Name: field})
+	}
+	return rest, computed, nil
+}
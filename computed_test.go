@@ -0,0 +1,144 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+var windowTests = []expressionTest{
+	{
+		Name: "Window with partition and order",
+		in: &Window{
+			Func:        RowNumber,
+			PartitionBy: []string{"author_id"},
+			OrderBy:     OrderBy{{FieldName: "created_at", Order: qparser.OrderDesc}},
+		},
+		out: "ROW_NUMBER() OVER (PARTITION BY author_id ORDER BY created_at DESC)",
+	},
+	{
+		Name: "Window with order only",
+		in:   &Window{Func: Rank, OrderBy: OrderBy{{FieldName: "score", Order: qparser.OrderDesc}}},
+		out:  "RANK() OVER (ORDER BY score DESC)",
+	},
+	{
+		Name: "Window with aggregate function",
+		in:   &Window{Func: &RawSQLWithArgs{SQL: "SUM(amount * ?)", Args: []interface{}{2}}, PartitionBy: []string{"a", "b"}},
+		out:  "SUM(amount * ?) OVER (PARTITION BY a, b)",
+		args: []interface{}{2},
+	},
+	{
+		Name:      "Window without function",
+		in:        &Window{},
+		expectErr: true,
+	},
+}
+
+func TestWindow(t *testing.T) {
+	for _, tt := range windowTests {
+		expr, args, err := tt.in.ToSQL()
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: unexpected error status: got %v, want %v", tt.Name, err, tt.expectErr)
+			continue
+		}
+		if tt.expectErr {
+			continue
+		}
+		if expr != tt.out {
+			t.Errorf("%s: expected expression %q, got %q", tt.Name, tt.out, expr)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %+v, got %+v", tt.Name, tt.args, args)
+		}
+	}
+}
+
+type computedFieldsTest struct {
+	query     string
+	sql       string
+	args      []interface{}
+	forbidden bool
+	expectErr bool
+}
+
+var computedFieldsTests = []computedFieldsTest{
+	{query: "", sql: "SELECT id, title FROM articles"},
+	{
+		query: "fields[articles]=id,rowNumber",
+		sql:   "SELECT id, ROW_NUMBER() OVER (PARTITION BY author_id ORDER BY created_at DESC) AS rowNumber FROM articles",
+	},
+	{
+		query: "fields[articles]=score,score",
+		sql:   "SELECT (likes * ? + comments) AS score FROM articles",
+		args:  []interface{}{2},
+	},
+	{
+		query: "fields[articles]=id,score&sort=-score,id",
+		sql:   "SELECT id, (likes * ? + comments) AS score FROM articles ORDER BY (likes * ? + comments) DESC, id ASC",
+		args:  []interface{}{2, 2},
+	},
+	{query: "sort=rowNumber", expectErr: true},
+	{query: "fields[articles]=secret", forbidden: true},
+	{query: "sort=secret", forbidden: true},
+}
+
+func TestComputedFields(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"articles",
+		MapTranslator(map[string]string{"id": "id", "title": "title"}),
+		WithDefaultFields([]string{"id", "title"}),
+		AllowSortingByFields([]string{"id"}),
+		WithComputedFields(map[string]ComputedField{
+			"rowNumber": {
+				Expr: &Window{
+					Func:        RowNumber,
+					PartitionBy: []string{"author_id"},
+					OrderBy:     OrderBy{{FieldName: "created_at", Order: qparser.OrderDesc}},
+				},
+			},
+			"score": {
+				Expr:     &RawSQLWithArgs{SQL: "(likes * ? + comments)", Args: []interface{}{2}},
+				Sortable: true,
+			},
+			"secret": {Expr: RawSQL("secret_rank"), Sortable: true},
+		}),
+		WithPolicy(func(ctx context.Context) (*Permissions, error) {
+			return &Permissions{
+				SelectFields: []string{"id", "title", "rowNumber", "score"},
+				SortFields:   []string{"id", "score"},
+			}, nil
+		}),
+	)
+	for _, tt := range computedFieldsTests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb, err := builder.Build(context.Background(), query)
+		var forbiddenErr *ForbiddenError
+		if errors.As(err, &forbiddenErr) != tt.forbidden {
+			t.Errorf("query %q: unexpected forbidden error status: got %v", tt.query, err)
+			continue
+		}
+		if (err != nil) != (tt.expectErr || tt.forbidden) {
+			t.Errorf("query %q: unexpected error status: got %v", tt.query, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		sql, args, err := sb.ToSQL()
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		if sql != tt.sql {
+			t.Errorf("query %q:\n\texpected sql %q\n\tgot          %q", tt.query, tt.sql, sql)
+		}
+		if len(tt.args) > 0 && !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("query %q: expected args %+v, got %+v", tt.query, tt.args, args)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package q2sql
+
+import (
+	"bytes"
+	"strings"
+)
+
+// CTE is a common table expression: name (column, ...) AS (query)
+type CTE struct {
+	Name      string
+	Columns   []string
+	Recursive bool
+	Query     Sqlizer
+}
+
+func (c CTE) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(c)
+}
+
+func (c CTE) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(c.Name)
+	if len(c.Columns) > 0 {
+		buf.WriteString(" (")
+		buf.WriteString(strings.Join(c.Columns, ", "))
+		buf.WriteByte(')')
+	}
+	buf.WriteString(" AS ")
+	return (&SubQuery{Query: c.Query}).WriteSQL(buf, args)
+}
+
+// writeCTEs writes the "WITH" statement,
+// the RECURSIVE keyword is added if any of the expressions is recursive
+func writeCTEs(ctes []CTE, sql *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	recursive := false
+	parts := make([]Sqlizer, len(ctes))
+	for i, cte := range ctes {
+		recursive = recursive || cte.Recursive
+		parts[i] = cte
+	}
+	sql.WriteString("WITH ")
+	if recursive {
+		sql.WriteString("RECURSIVE ")
+	}
+	args, err := appendToSQL(parts, sql, ", ", args)
+	if err != nil {
+		return nil, err
+	}
+	sql.WriteString(" ")
+	return args, nil
+}
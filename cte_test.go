@@ -0,0 +1,115 @@
+package q2sql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+var cteTests = []selectBuilderTest{
+	{
+		b: new(SelectBuilder).
+			With("latest", &RawSQLWithArgs{
+				SQL:  "SELECT DISTINCT ON (document_id) * FROM revisions WHERE created_at > ? ORDER BY document_id, created_at DESC",
+				Args: []interface{}{"2023-01-01"},
+			}).
+			Select([]string{"id", "title"}).
+			From("latest").
+			Where(&Eq{Field: "status", Value: "published"}),
+		query: "WITH latest AS (SELECT DISTINCT ON (document_id) * FROM revisions WHERE created_at > ? " +
+			"ORDER BY document_id, created_at DESC) SELECT id, title FROM latest WHERE status = ?",
+		args: []interface{}{"2023-01-01", "published"},
+	},
+	{
+		b: func() *SelectBuilder {
+			b := new(SelectBuilder).
+				With("roots", new(SelectBuilder).Select([]string{"id"}).From("categories").Where(&Eq{Field: "id", Value: 1})).
+				WithRecursive("tree", &RawSQLWithArgs{
+					SQL:  "SELECT id, parent_id FROM roots UNION ALL SELECT c.id, c.parent_id FROM categories c JOIN tree t ON c.parent_id = t.id",
+					Args: nil,
+				}).
+				Select([]string{"id"}).
+				From("tree").
+				Where(&Neq{Field: "id", Value: 5})
+			b.CTEs[1].Columns = []string{"id", "parent_id"}
+			return b
+		}(),
+		query: "WITH RECURSIVE roots AS (SELECT id FROM categories WHERE id = ?), " +
+			"tree (id, parent_id) AS (SELECT id, parent_id FROM roots UNION ALL SELECT c.id, c.parent_id FROM categories c JOIN tree t ON c.parent_id = t.id) " +
+			"SELECT id FROM tree WHERE id != ?",
+		args: []interface{}{1, 5},
+	},
+	{
+		b:   new(SelectBuilder).With("broken", new(SelectBuilder)).Select([]string{"id"}).From("broken"),
+		err: true,
+	},
+}
+
+func TestSelectBuilderCTE(t *testing.T) {
+	for i, tt := range cteTests {
+		meta := fmt.Sprintf("test %d", i)
+		sql, args, err := tt.b.ToSQL()
+		if (err != nil) != tt.err {
+			t.Errorf("%s: unexpected error status, got %v", meta, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sql != tt.query {
+			t.Errorf("%s: expected query %q\n\tgot %q", meta, tt.query, sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %+v, got %+v", meta, tt.args, args)
+		}
+	}
+}
+
+func TestResourceSelectBuilderWithCTE(t *testing.T) {
+	latest := CTE{
+		Name: "latest_revisions",
+		Query: &RawSQLWithArgs{
+			SQL:  "SELECT * FROM revisions WHERE deleted = ?",
+			Args: []interface{}{false},
+		},
+	}
+	builder := NewResourceSelectBuilder(
+		"latest_revisions",
+		MapTranslator(map[string]string{"id": "id", "title": "title"}),
+		WithDefaultFields([]string{"id", "title"}),
+		AllowFiltering(
+			AllowedConditions{"title": []string{filterEq}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithCommonTableExpressions(latest),
+	)
+	query, err := qparser.ParseQuery("fields[latest_revisions]=id&filter[title]=eq:x")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb := new(SelectBuilder)
+	for i := 0; i < 2; i++ {
+		// the expression must not be duplicated when the builder is reused
+		sb.Columns = nil
+		sb.WhereParts = nil
+		if _, err = builder.Build(context.Background(), query, sb); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+	sql, args, err := sb.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "WITH latest_revisions AS (SELECT * FROM revisions WHERE deleted = ?) " +
+		"SELECT id FROM latest_revisions WHERE title = ?"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q\n\tgot %q", expectedSQL, sql)
+	}
+	if expectedArgs := []interface{}{false, "x"}; !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %+v, got %+v", expectedArgs, args)
+	}
+}
@@ -0,0 +1,152 @@
+package q2sql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InterpolateUnsafe renders the statement with the arguments inlined as literals of the dialect.
+//
+// The result is intended for logging and debugging only. It is NOT SAFE to execute it,
+// the escaping is not guaranteed to match the database settings, so it could be
+// vulnerable to SQL injection. Use ToSQL and pass the arguments to the driver instead
+func InterpolateUnsafe(s Sqlizer, dialect Dialect) (string, error) {
+	sql, args, err := s.ToSQL()
+	if err != nil {
+		return "", err
+	}
+	return InterpolateArgsUnsafe(sql, args, dialect)
+}
+
+// InterpolateArgsUnsafe replaces "?" placeholders outside of quoted strings with the literals
+// of the arguments. See InterpolateUnsafe, the result must not be executed
+func InterpolateArgsUnsafe(sql string, args []interface{}, dialect Dialect) (string, error) {
+	buf := &strings.Builder{}
+	n := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			if n >= len(args) {
+				return "", fmt.Errorf("not enough arguments for the placeholders: got %d", len(args))
+			}
+			buf.WriteString(sqlLiteral(args[n], dialect))
+			n++
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("%d arguments are passed for %d placeholders", len(args), n)
+	}
+	return buf.String(), nil
+}
+
+// sqlLiteral formats the value as the literal of the dialect
+func sqlLiteral(arg interface{}, dialect Dialect) string {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return quoteString(fmt.Sprint(arg), dialect)
+	}
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if dialect == DialectSQLite {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		if dialect == DialectPostgres {
+			return `'\x` + hex.EncodeToString(v) + "'"
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		if dialect == DialectMySQL {
+			return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+		}
+		return "'" + v.Format("2006-01-02 15:04:05.999999Z07:00") + "'"
+	case string:
+		return quoteString(v, dialect)
+	}
+	return quoteString(fmt.Sprint(value), dialect)
+}
+
+func quoteString(s string, dialect Dialect) string {
+	if dialect == DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// clauseKeywords are keywords which start a new line in PrettySQL,
+// the longer keywords are placed before the keywords which they contain
+var clauseKeywords = []string{
+	"SELECT", "FROM",
+	"LEFT JOIN", "RIGHT JOIN", "INNER JOIN", "FULL JOIN", "CROSS JOIN", "JOIN",
+	"WHERE", "GROUP BY", "HAVING", "ORDER BY", "LIMIT", "OFFSET",
+	"UNION ALL", "UNION", "INTERSECT", "EXCEPT",
+	"SET", "RETURNING", "FOR",
+}
+
+// PrettySQL puts each clause of the statement on its own line,
+// clauses of subqueries (in parentheses) are kept as is
+func PrettySQL(sql string) string {
+	buf := &strings.Builder{}
+	depth := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ' ' && depth == 0:
+			if keyword := clauseKeywordAt(sql[i+1:]); keyword != "" {
+				buf.WriteByte('\n')
+				buf.WriteString(keyword)
+				i += len(keyword)
+				continue
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+func clauseKeywordAt(s string) string {
+	for _, keyword := range clauseKeywords {
+		if !strings.HasPrefix(s, keyword) {
+			continue
+		}
+		if len(s) == len(keyword) || s[len(keyword)] == ' ' {
+			return keyword
+		}
+	}
+	return ""
+}
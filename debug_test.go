@@ -0,0 +1,133 @@
+package q2sql
+
+import (
+	"testing"
+	"time"
+)
+
+type customStatus string
+
+type interpolateTest struct {
+	name      string
+	dialect   Dialect
+	sql       string
+	args      []interface{}
+	out       string
+	expectErr bool
+}
+
+var interpolateTests = []interpolateTest{
+	{
+		name: "scalars",
+		sql:  "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND d IS ? AND e = ? AND f = ?",
+		args: []interface{}{42, 1.5, true, nil, uint8(7), customStatus("new")},
+		out:  "SELECT * FROM t WHERE a = 42 AND b = 1.5 AND c = TRUE AND d IS NULL AND e = 7 AND f = 'new'",
+	},
+	{
+		name:    "strings in postgres",
+		dialect: DialectPostgres,
+		sql:     "SELECT * FROM t WHERE name = ?",
+		args:    []interface{}{`O'Reilly \n`},
+		out:     `SELECT * FROM t WHERE name = 'O''Reilly \n'`,
+	},
+	{
+		name:    "strings in mysql",
+		dialect: DialectMySQL,
+		sql:     "SELECT * FROM t WHERE name = ?",
+		args:    []interface{}{`O'Reilly \n`},
+		out:     `SELECT * FROM t WHERE name = 'O''Reilly \\n'`,
+	},
+	{
+		name:    "bytes and booleans in sqlite",
+		dialect: DialectSQLite,
+		sql:     "SELECT * FROM t WHERE data = ? AND active = ?",
+		args:    []interface{}{[]byte{0xde, 0xad}, false},
+		out:     "SELECT * FROM t WHERE data = X'dead' AND active = 0",
+	},
+	{
+		name:    "bytes in postgres",
+		dialect: DialectPostgres,
+		sql:     "SELECT * FROM t WHERE data = ?",
+		args:    []interface{}{[]byte{0xbe, 0xef}},
+		out:     `SELECT * FROM t WHERE data = '\xbeef'`,
+	},
+	{
+		name:    "time in postgres",
+		dialect: DialectPostgres,
+		sql:     "SELECT * FROM t WHERE created_at > ?",
+		args:    []interface{}{time.Date(2023, time.March, 15, 10, 30, 45, 500000000, time.UTC)},
+		out:     "SELECT * FROM t WHERE created_at > '2023-03-15 10:30:45.5Z'",
+	},
+	{
+		name:    "time in mysql",
+		dialect: DialectMySQL,
+		sql:     "SELECT * FROM t WHERE created_at > ?",
+		args:    []interface{}{time.Date(2023, time.March, 15, 10, 30, 45, 0, time.UTC)},
+		out:     "SELECT * FROM t WHERE created_at > '2023-03-15 10:30:45'",
+	},
+	{
+		name: "placeholders in quoted strings are kept",
+		sql:  "SELECT '?' AS q, \"a?\" FROM t WHERE a = ?",
+		args: []interface{}{1},
+		out:  "SELECT '?' AS q, \"a?\" FROM t WHERE a = 1",
+	},
+	{
+		name:      "not enough arguments",
+		sql:       "SELECT * FROM t WHERE a = ? AND b = ?",
+		args:      []interface{}{1},
+		expectErr: true,
+	},
+	{
+		name:      "too many arguments",
+		sql:       "SELECT * FROM t WHERE a = ?",
+		args:      []interface{}{1, 2},
+		expectErr: true,
+	},
+}
+
+func TestInterpolateArgsUnsafe(t *testing.T) {
+	for _, tt := range interpolateTests {
+		out, err := InterpolateArgsUnsafe(tt.sql, tt.args, tt.dialect)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: unexpected error status: got %v, want %v", tt.name, err, tt.expectErr)
+			continue
+		}
+		if out != tt.out {
+			t.Errorf("%s:\n\texpected %q\n\tgot      %q", tt.name, tt.out, out)
+		}
+	}
+}
+
+func TestInterpolateUnsafe(t *testing.T) {
+	b := new(SelectBuilder).
+		Select([]string{"id"}).
+		From("articles").
+		Where(&Eq{Field: "title", Value: "it's"}, &In{Field: "id", Values: []interface{}{1, 2}})
+	out, err := InterpolateUnsafe(b, DialectPostgres)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expected = "SELECT id FROM articles WHERE title = 'it''s' AND id IN (1,2)"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestPrettySQL(t *testing.T) {
+	sql := "SELECT id, title FROM articles LEFT JOIN users ON users.id = articles.author_id " +
+		"WHERE title = 'ORDER BY x' AND id IN (SELECT article_id FROM tags WHERE name = ?) " +
+		"GROUP BY id HAVING COUNT(*) > ? ORDER BY id DESC LIMIT 10 OFFSET 20 FOR UPDATE SKIP LOCKED"
+	expected := "SELECT id, title\n" +
+		"FROM articles\n" +
+		"LEFT JOIN users ON users.id = articles.author_id\n" +
+		"WHERE title = 'ORDER BY x' AND id IN (SELECT article_id FROM tags WHERE name = ?)\n" +
+		"GROUP BY id\n" +
+		"HAVING COUNT(*) > ?\n" +
+		"ORDER BY id DESC\n" +
+		"LIMIT 10\n" +
+		"OFFSET 20\n" +
+		"FOR UPDATE SKIP LOCKED"
+	if out := PrettySQL(sql); out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
@@ -0,0 +1,144 @@
+package q2sql
+
+import "sort"
+
+// Capabilities describes what the builder supports, it is JSON serializable
+// so it could be served to the clients or used by the tooling.
+// The fields are reported by API names if WithReverseTranslator is used
+type Capabilities struct {
+	Resource              string              `json:"resource"`
+	Fields                []FieldCapabilities `json:"fields"`
+	DefaultFields         []string            `json:"defaultFields"`
+	AlwaysSelectFields    []string            `json:"alwaysSelectFields,omitempty"`
+	AlwaysSelectAllFields bool                `json:"alwaysSelectAllFields,omitempty"`
+	SortFields            []string            `json:"sortFields"`
+	Pagination            []PageParameter     `json:"pagination,omitempty"`
+	Limits                *Limits             `json:"limits,omitempty"`
+	Dialect               Dialect             `json:"dialect,omitempty"`
+}
+
+// FieldCapabilities describes what could be done with the field
+type FieldCapabilities struct {
+	Name string `json:"name"`
+	// Column is empty for the computed fields and the sort expressions
+	Column     string    `json:"column,omitempty"`
+	Type       FieldType `json:"type,omitempty"`
+	Selectable bool      `json:"selectable"`
+	Sortable   bool      `json:"sortable"`
+	Computed   bool      `json:"computed,omitempty"`
+	// RelativeTime means that the filter values are the time expressions, see WithRelativeTimeFilter
+	RelativeTime bool     `json:"relativeTime,omitempty"`
+	Conditions   []string `json:"conditions,omitempty"`
+}
+
+// Describe returns capabilities of the builder, the result is a copy
+// so changing it does not affect the builder
+func (s *ResourceSelectBuilder) Describe() *Capabilities {
+	sortFields, _ := s.sortableNames()
+	c := &Capabilities{
+		Resource:              s.resourceName,
+		Fields:                s.describeFields(),
+		DefaultFields:         s.clientNames(s.defaultFields),
+		AlwaysSelectFields:    s.clientNames(s.alwaysSelectFields),
+		AlwaysSelectAllFields: s.alwaysSelectAllFields,
+		SortFields:            append([]string{}, sortFields...),
+		Pagination:            append([]PageParameter(nil), s.pageParameters...),
+		Dialect:               s.dialect,
+	}
+	if s.limits != nil {
+		limits := *s.limits
+		c.Limits = &limits
+	}
+	return c
+}
+
+// describeFields merges the capabilities of the same column, so the field is listed once
+// even if its API name is unknown for the selectable and sortable columns (no reverse translator)
+func (s *ResourceSelectBuilder) describeFields() []FieldCapabilities {
+	byColumn := make(map[string]*FieldCapabilities)
+	byName := make(map[string]*FieldCapabilities)
+	column := func(column string) *FieldCapabilities {
+		f, ok := byColumn[column]
+		if !ok {
+			name := s.clientName(column)
+			f = &FieldCapabilities{Name: name, Column: column, Type: s.fieldTypes[name]}
+			byColumn[column] = f
+		}
+		return f
+	}
+	named := func(name string) *FieldCapabilities {
+		f, ok := byName[name]
+		if !ok {
+			f = &FieldCapabilities{Name: name, Type: s.fieldTypes[name]}
+			byName[name] = f
+		}
+		return f
+	}
+	for _, c := range s.allowedSelectFieldsSlc {
+		if c != "*" {
+			column(c).Selectable = true
+		}
+	}
+	for _, c := range s.allowedSortFields {
+		column(c).Sortable = true
+	}
+	for name := range s.sortExpressions {
+		named(name).Sortable = true
+	}
+	for name, computed := range s.computedFields {
+		f := named(name)
+		f.Selectable = true
+		f.Computed = true
+		f.Sortable = f.Sortable || computed.Sortable
+	}
+	for _, name := range sortedKeys(s.allowedConditions) {
+		f := s.filterField(name, byName, column)
+		_, f.RelativeTime = s.relativeTimeFields[name]
+		f.Conditions = append([]string(nil), s.allowedConditions[name]...)
+		sort.Strings(f.Conditions)
+	}
+
+	result := make([]FieldCapabilities, 0, len(byColumn)+len(byName))
+	for _, f := range byColumn {
+		result = append(result, *f)
+	}
+	for _, f := range byName {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// filterField returns the capabilities of the filtered field: the computed field or the sort expression
+// of the same name, otherwise the one of the translated column. The filter name is the API name,
+// so it replaces the column name used when the reverse translator does not know the column
+func (s *ResourceSelectBuilder) filterField(
+	name string,
+	byName map[string]*FieldCapabilities,
+	column func(string) *FieldCapabilities,
+) *FieldCapabilities {
+	if f, ok := byName[name]; ok {
+		return f
+	}
+	columns, err := s.translator([]string{name})
+	if err != nil || len(columns) != 1 {
+		f := &FieldCapabilities{Name: name, Type: s.fieldTypes[name]}
+		byName[name] = f
+		return f
+	}
+	f := column(columns[0])
+	if _, ok := s.apiName(f.Column); !ok {
+		f.Name = name
+		f.Type = s.fieldTypes[name]
+	}
+	return f
+}
+
+// clientNames translates the columns back to API names, see clientName
+func (s *ResourceSelectBuilder) clientNames(columns []string) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = s.clientName(column)
+	}
+	return names
+}
@@ -0,0 +1,89 @@
+package q2sql
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	builder := newOpenAPITestBuilder(t,
+		AlwaysSelectFields([]string{"id"}),
+		WithComputedFields(map[string]ComputedField{"rank": {Expr: RowNumber, Sortable: true}}),
+		WithLimits(Limits{MaxFilters: 5}),
+	)
+	c := builder.Describe()
+
+	if c.Resource != "articles" {
+		t.Errorf("unexpected resource %q", c.Resource)
+	}
+	wantFields := []FieldCapabilities{
+		{Name: "createdAt", Column: "created_at", Type: FieldTypeDateTime, Selectable: true, Sortable: true, Conditions: []string{"gt"}},
+		{Name: "id", Column: "id", Type: FieldTypeInteger, Selectable: true, Conditions: []string{filterAny, filterEq}},
+		{Name: "rank", Selectable: true, Sortable: true, Computed: true},
+		{Name: "relevance", Sortable: true},
+		{Name: "title", Column: "title", Selectable: true},
+	}
+	if !reflect.DeepEqual(c.Fields, wantFields) {
+		t.Errorf("expected fields\n%+v\ngot\n%+v", wantFields, c.Fields)
+	}
+	if !reflect.DeepEqual(c.DefaultFields, []string{"id", "title"}) {
+		t.Errorf("unexpected default fields %v", c.DefaultFields)
+	}
+	if !reflect.DeepEqual(c.AlwaysSelectFields, []string{"id"}) {
+		t.Errorf("unexpected always selected fields %v", c.AlwaysSelectFields)
+	}
+	if !reflect.DeepEqual(c.SortFields, []string{"createdAt", "rank", "relevance"}) {
+		t.Errorf("unexpected sort fields %v", c.SortFields)
+	}
+	if !reflect.DeepEqual(c.Pagination, []PageParameter{{Name: "limit", Type: FieldTypeInteger, Maximum: 100}}) {
+		t.Errorf("unexpected pagination %+v", c.Pagination)
+	}
+	if c.Limits == nil || c.Limits.MaxFilters != 5 {
+		t.Errorf("unexpected limits %+v", c.Limits)
+	}
+
+	c.Fields[0].Conditions[0] = "changed"
+	if builder.Describe().Fields[0].Conditions[0] != "gt" {
+		t.Error("changing capabilities must not affect the builder")
+	}
+}
+
+func TestDescribeWithoutReverseTranslator(t *testing.T) {
+	builder := newArticlesBuilder(
+		AllowSelectFields([]string{"id", "created_at"}),
+		AllowFiltering(
+			AllowedConditions{"createdAt": {"gt"}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithFieldTypes(map[string]FieldType{"createdAt": FieldTypeDateTime}),
+	)
+	want := []FieldCapabilities{
+		{Name: "createdAt", Column: "created_at", Type: FieldTypeDateTime, Selectable: true, Sortable: true, Conditions: []string{"gt"}},
+		{Name: "id", Column: "id", Selectable: true},
+	}
+	if fields := builder.Describe().Fields; !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected fields\n%+v\ngot\n%+v", want, fields)
+	}
+}
+
+func TestDescribeJSON(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"articles",
+		MapTranslator(map[string]string{"title": "title"}),
+		WithDefaultFields([]string{"title"}),
+		AllowFiltering(AllowedConditions{"title": {filterEq}}, ConditionMap{}, DefaultFilterExpressionParser),
+		WithLimits(Limits{MaxSortFields: 2}),
+	)
+	data, err := json.Marshal(builder.Describe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"resource":"articles",` +
+		`"fields":[{"name":"title","column":"title","selectable":true,"sortable":false,"conditions":["eq"]}],` +
+		`"defaultFields":["title"],"sortFields":[],"limits":{"maxSortFields":2}}`
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+}
@@ -0,0 +1,16 @@
+package q2sql
+
+// Dialect specifies the database which the SQL is rendered for.
+// Dialect specific clauses (such as row locking) are validated against the dialect,
+// DialectGeneric renders them as is
+type Dialect string
+
+const (
+	DialectGeneric  Dialect = ""
+	DialectMySQL    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// ErrUnsupportedByDialect is returned when a clause is not supported by the dialect
+const ErrUnsupportedByDialect = Error("clause is not supported by the dialect")
@@ -0,0 +1,156 @@
+package q2sql
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/velmie/qparser"
+)
+
+const (
+	fieldsParameter  = "fields"
+	filterParameter  = "filter"
+	sortParameter    = "sort"
+	pageParameter    = "page"
+	includeParameter = "include"
+)
+
+// EncodeQuery renders the query back to the canonical query string without the leading "?".
+// The parameters are rendered in the fixed order: fields (ordered by the resource), filter, sort,
+// page (ordered by the key), include and then other parameters ordered by the key.
+// Order of the requested fields, filters and sort fields is preserved
+//
+// It is intended for creation of links e.g. JSON:API "links.next"
+func EncodeQuery(query *qparser.Query) string {
+	return encodeQuery(query, false)
+}
+
+// NormalizeQuery renders the query to the normalized query string which does not depend
+// on the order of the requested fields and filters, it could be used as a cache key.
+// Unlike EncodeQuery the lists of fields are sorted and filters are ordered by the field and predicate
+func NormalizeQuery(query *qparser.Query) string {
+	return encodeQuery(query, true)
+}
+
+func encodeQuery(query *qparser.Query, normalize bool) string {
+	if query == nil {
+		return ""
+	}
+	var params []string
+	resources := make([]string, 0, len(query.Fields))
+	for resource := range query.Fields {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		fields := query.Fields[resource]
+		if normalize {
+			fields = append([]string(nil), fields...)
+			sort.Strings(fields)
+		}
+		params = append(params, encodeParameter(fieldsParameter, []string{resource}, strings.Join(fields, ",")))
+	}
+
+	filters := query.Filters
+	if normalize {
+		filters = append([]qparser.Filter(nil), filters...)
+		sort.SliceStable(filters, func(i, j int) bool {
+			if filters[i].FieldName != filters[j].FieldName {
+				return filters[i].FieldName < filters[j].FieldName
+			}
+			return filters[i].Predicate < filters[j].Predicate
+		})
+	}
+	for _, filter := range filters {
+		params = append(params, encodeParameter(filterParameter, []string{filter.FieldName}, filter.Predicate))
+	}
+
+	if len(query.Sort) > 0 {
+		sortList := make([]string, len(query.Sort))
+		for i, s := range query.Sort {
+			sortList[i] = encodeSort(s)
+		}
+		params = append(params, encodeParameter(sortParameter, nil, strings.Join(sortList, ",")))
+	}
+
+	if page := query.Page; page != nil {
+		for _, p := range [][2]string{
+			{"cursor", page.Cursor},
+			{"limit", page.Limit},
+			{"number", page.Number},
+			{"offset", page.Offset},
+			{"size", page.Size},
+		} {
+			if p[1] != "" {
+				params = append(params, encodeParameter(pageParameter, []string{p[0]}, p[1]))
+			}
+		}
+	}
+
+	if len(query.Includes) > 0 {
+		params = append(params, encodeParameter(includeParameter, nil, strings.Join(includePaths(query.Includes, ""), ",")))
+	}
+
+	return strings.Join(append(params, encodeOtherValues(query.Values, normalize)...), "&")
+}
+
+// encodeSort returns the sort field as it is written in the "sort" parameter
+func encodeSort(sort qparser.Sort) string {
+	if sort.Order == qparser.OrderDesc {
+		return "-" + sort.FieldName
+	}
+	return sort.FieldName
+}
+
+func encodeOtherValues(values qparser.Values, normalize bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		switch key {
+		case fieldsParameter, filterParameter, sortParameter, pageParameter, includeParameter:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		encoded := make([]string, len(values[key]))
+		for i, value := range values[key] {
+			encoded[i] = encodeParameter(key, value.NestedKeys, value.Value)
+		}
+		if normalize {
+			sort.Strings(encoded)
+		}
+		params = append(params, encoded...)
+	}
+	return params
+}
+
+func includePaths(includes []qparser.Include, prefix string) []string {
+	var paths []string
+	for _, include := range includes {
+		path := prefix + include.Relation
+		if len(include.Includes) == 0 {
+			paths = append(paths, path)
+			continue
+		}
+		paths = append(paths, includePaths(include.Includes, path+".")...)
+	}
+	return paths
+}
+
+var valueUnescaper = strings.NewReplacer("%2C", ",", "%3A", ":", "%2A", "*")
+
+func encodeParameter(key string, nestedKeys []string, value string) string {
+	var sb strings.Builder
+	sb.WriteString(url.QueryEscape(key))
+	for _, nested := range nestedKeys {
+		sb.WriteByte('[')
+		sb.WriteString(url.QueryEscape(nested))
+		sb.WriteByte(']')
+	}
+	sb.WriteByte('=')
+	sb.WriteString(valueUnescaper.Replace(url.QueryEscape(value)))
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package q2sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type encodeQueryTest struct {
+	query      string
+	encoded    string
+	normalized string
+}
+
+var encodeQueryTests = []encodeQueryTest{
+	{
+		query:      "",
+		encoded:    "",
+		normalized: "",
+	},
+	{
+		query: "sort=-createdAt,title&fields[users]=name&fields[articles]=title,id" +
+			"&filter[title]=contains:bit coin&filter[id]=in:1,2&page[offset]=20&page[limit]=10",
+		encoded: "fields[articles]=title,id&fields[users]=name&filter[title]=contains:bit+coin&filter[id]=in:1,2" +
+			"&sort=-createdAt,title&page[limit]=10&page[offset]=20",
+		normalized: "fields[articles]=id,title&fields[users]=name&filter[id]=in:1,2&filter[title]=contains:bit+coin" +
+			"&sort=-createdAt,title&page[limit]=10&page[offset]=20",
+	},
+	{
+		query:      "origin=53.9,27.56&include=comments.author,tags&filter[createdAt]=gt:now%2B1d&custom[b]=2&custom[a]=1",
+		encoded:    "filter[createdAt]=gt:now%2B1d&include=comments.author,tags&custom[b]=2&custom[a]=1&origin=53.9,27.56",
+		normalized: "filter[createdAt]=gt:now%2B1d&include=comments.author,tags&custom[a]=1&custom[b]=2&origin=53.9,27.56",
+	},
+}
+
+func TestEncodeQuery(t *testing.T) {
+	for _, tt := range encodeQueryTests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("qparser.ParseQuery(%q) returned unexpected error: %s", tt.query, err)
+		}
+		encoded := EncodeQuery(query)
+		if encoded != tt.encoded {
+			t.Errorf("EncodeQuery(%q):\n\tgot  %q\n\twant %q", tt.query, encoded, tt.encoded)
+		}
+		if normalized := NormalizeQuery(query); normalized != tt.normalized {
+			t.Errorf("NormalizeQuery(%q):\n\tgot  %q\n\twant %q", tt.query, normalized, tt.normalized)
+		}
+		// the encoded query must be parsed to the same query
+		parsed, err := qparser.ParseQuery(encoded)
+		if err != nil {
+			t.Fatalf("qparser.ParseQuery(%q) returned unexpected error: %s", encoded, err)
+		}
+		if !reflect.DeepEqual(parsed.Fields, query.Fields) ||
+			!reflect.DeepEqual(parsed.Filters, query.Filters) ||
+			!reflect.DeepEqual(parsed.Sort, query.Sort) ||
+			!reflect.DeepEqual(parsed.Page, query.Page) ||
+			!reflect.DeepEqual(parsed.Includes, query.Includes) {
+			t.Errorf("EncodeQuery(%q) = %q is parsed to the different query", tt.query, encoded)
+		}
+	}
+	if EncodeQuery(nil) != "" {
+		t.Error("expected empty string for nil query")
+	}
+}
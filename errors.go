@@ -30,3 +30,16 @@ type TranslationError struct {
 func (t *TranslationError) Error() string {
 	return fmt.Sprintf("failed to translate format of the %q entry because %s", t.Entry, t.Message)
 }
+
+// ForbiddenError is returned when the field is known but the policy does not permit
+// to use it in the request, unlike other errors caused by the query
+// it should be reported to the client as "403 Forbidden"
+type ForbiddenError struct {
+	Filter  string
+	Field   string
+	Message string
+}
+
+func (f *ForbiddenError) Error() string {
+	return f.Message
+}
@@ -0,0 +1,87 @@
+package q2sql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier executes queries, it is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// RowScanner scans the current row, it is called for each row of the result set
+type RowScanner func(rows *sql.Rows) error
+
+// Executor executes queries built by the select builder
+type Executor struct {
+	db     Querier
+	tracer Tracer
+	logger Logger
+}
+
+// ExecutorOption configures the Executor
+type ExecutorOption func(e *Executor)
+
+// WithExecutorTracer reports spans of the executed queries to the tracer
+func WithExecutorTracer(tracer Tracer) ExecutorOption {
+	return func(e *Executor) {
+		e.tracer = tracer
+	}
+}
+
+// WithExecutorLogger reports executed queries to the logger at the debug level
+func WithExecutorLogger(logger Logger) ExecutorOption {
+	return func(e *Executor) {
+		e.logger = logger
+	}
+}
+
+// NewExecutor is Executor constructor
+func NewExecutor(db Querier, options ...ExecutorOption) *Executor {
+	e := &Executor{db: db}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// Select executes the query and calls scan for each row.
+// If the builder page info has the FetchExtra flag the extra row is not scanned
+// and hasNext reports whether it exists, otherwise hasNext is always false
+func (e *Executor) Select(ctx context.Context, b *SelectBuilder, scan RowScanner) (hasNext bool, err error) {
+	query, args, err := b.ToSQL()
+	if err != nil {
+		return false, err
+	}
+	var scanned uint64
+	if e.tracer != nil || e.logger != nil {
+		var span Span
+		ctx, span = startSpan(ctx, e.tracer, SpanSelect)
+		defer func() {
+			attributes := []Attribute{{Key: AttributeStatement, Value: query}, {Key: AttributeRows, Value: scanned}}
+			observe(ctx, span, e.logger, "q2sql: query executed", err, attributes)
+			span.End()
+		}()
+	}
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if b.Page != nil && b.Page.FetchExtra && scanned == b.Page.Limit {
+			hasNext = true
+			break
+		}
+		if err = scan(rows); err != nil {
+			return false, err
+		}
+		scanned++
+	}
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	return hasNext, rows.Close()
+}
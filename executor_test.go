@@ -0,0 +1,165 @@
+package q2sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeDriver returns the configured rows for any query and records executed queries
+type fakeDriver struct {
+	mu      sync.Mutex
+	rows    [][]driver.Value
+	queries []string
+	args    [][]driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+var fakeDriverSeq int
+
+func openFakeDB(t *testing.T, rows [][]driver.Value) (*sql.DB, *fakeDriver) {
+	t.Helper()
+	d := &fakeDriver{rows: rows}
+	fakeDriverSeq++
+	name := fmt.Sprintf("q2sqlfake%d", fakeDriverSeq)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, d
+}
+
+type executorTest struct {
+	rows    int
+	page    *PageInfo
+	scanned []int64
+	hasNext bool
+}
+
+var executorTests = []executorTest{
+	{rows: 3, page: nil, scanned: []int64{1, 2, 3}, hasNext: false},
+	{rows: 3, page: &PageInfo{Limit: 2, FetchExtra: true}, scanned: []int64{1, 2}, hasNext: true},
+	{rows: 2, page: &PageInfo{Limit: 2, FetchExtra: true}, scanned: []int64{1, 2}, hasNext: false},
+	{rows: 3, page: &PageInfo{Limit: 3}, scanned: []int64{1, 2, 3}, hasNext: false},
+}
+
+func TestExecutorSelect(t *testing.T) {
+	ctx := context.Background()
+	for i, tt := range executorTests {
+		meta := fmt.Sprintf("test %d", i)
+		rows := make([][]driver.Value, tt.rows)
+		for j := range rows {
+			rows[j] = []driver.Value{int64(j + 1)}
+		}
+		db, d := openFakeDB(t, rows)
+		b := new(SelectBuilder).Select([]string{"id"}).From("articles").Where(&Eq{Field: "status", Value: "new"})
+		b.Page = tt.page
+		var scanned []int64
+		hasNext, err := NewExecutor(db).Select(ctx, b, func(rows *sql.Rows) error {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			scanned = append(scanned, id)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", meta, err)
+			continue
+		}
+		if hasNext != tt.hasNext {
+			t.Errorf("%s: expected hasNext %v, got %v", meta, tt.hasNext, hasNext)
+		}
+		if !reflect.DeepEqual(scanned, tt.scanned) {
+			t.Errorf("%s: expected scanned %v, got %v", meta, tt.scanned, scanned)
+		}
+		if len(d.queries) != 1 || d.queries[0] != "SELECT id FROM articles WHERE status = ?" {
+			t.Errorf("%s: unexpected executed queries %q", meta, d.queries)
+		}
+	}
+}
+
+func TestExecutorSelectScanError(t *testing.T) {
+	db, _ := openFakeDB(t, [][]driver.Value{{int64(1)}})
+	scanErr := errors.New("scan error")
+	b := new(SelectBuilder).Select([]string{"id"}).From("articles")
+	_, err := NewExecutor(db).Select(context.Background(), b, func(rows *sql.Rows) error {
+		return scanErr
+	})
+	if !errors.Is(err, scanErr) {
+		t.Errorf("expected scan error, got %v", err)
+	}
+	if _, err = NewExecutor(db).Select(context.Background(), new(SelectBuilder), nil); err == nil {
+		t.Error("expected error for the invalid select builder")
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	records := []string{"a", "b", "c"}
+	trimmed, hasNext := TrimPage(records, &PageInfo{Limit: 2, FetchExtra: true})
+	if !reflect.DeepEqual(trimmed, []string{"a", "b"}) || !hasNext {
+		t.Errorf("unexpected result %v %v", trimmed, hasNext)
+	}
+	trimmed, hasNext = TrimPage(records, &PageInfo{Limit: 3, FetchExtra: true})
+	if !reflect.DeepEqual(trimmed, records) || hasNext {
+		t.Errorf("unexpected result %v %v", trimmed, hasNext)
+	}
+	trimmed, hasNext = TrimPage(records, &PageInfo{Limit: 2})
+	if !reflect.DeepEqual(trimmed, records) || hasNext {
+		t.Errorf("unexpected result %v %v", trimmed, hasNext)
+	}
+	trimmed, hasNext = TrimPage(records, nil)
+	if !reflect.DeepEqual(trimmed, records) || hasNext {
+		t.Errorf("unexpected result %v %v", trimmed, hasNext)
+	}
+}
@@ -3,25 +3,35 @@ package q2sql
 import (
 	"bytes"
 	"fmt"
-	"io"
 	"strings"
 
 	"github.com/velmie/qparser"
 )
 
+// ErrUndefinedSubquery is returned when EXISTS or NOT EXISTS is rendered without a query
+const ErrUndefinedSubquery = Error("subquery is not defined")
+
 // Sqlizer return an SQL with the list of arguments
 type Sqlizer interface {
 	ToSQL() (string, []interface{}, error)
 }
 
 // Eq - Equality: field = value
+// if the value is Sqlizer (e.g. *SelectBuilder) it is rendered as a subquery: field = (SELECT ...),
+// the same applies to Neq, Lt, Le, Gt and Ge.
+// Note that this includes RawSQL and RawSQLWithArgs values: they are written into the query
+// instead of being bound as an argument, use a plain string to compare with a literal text
 type Eq struct {
 	Field string
 	Value interface{}
 }
 
 func (eq *Eq) ToSQL() (string, []interface{}, error) {
-	return eq.Field + " = ?", []interface{}{eq.Value}, nil
+	return writerToSQL(eq)
+}
+
+func (eq *Eq) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, eq.Field, " = ", eq.Value)
 }
 
 // Neq - Non-equality: field != value
@@ -31,7 +41,11 @@ type Neq struct {
 }
 
 func (neq *Neq) ToSQL() (string, []interface{}, error) {
-	return neq.Field + " != ?", []interface{}{neq.Value}, nil
+	return writerToSQL(neq)
+}
+
+func (neq *Neq) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, neq.Field, " != ", neq.Value)
 }
 
 // Lt - Less than: field < value
@@ -41,7 +55,11 @@ type Lt struct {
 }
 
 func (lt *Lt) ToSQL() (string, []interface{}, error) {
-	return lt.Field + " < ?", []interface{}{lt.Value}, nil
+	return writerToSQL(lt)
+}
+
+func (lt *Lt) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, lt.Field, " < ", lt.Value)
 }
 
 // Le - Less than or equal to: field < value
@@ -51,7 +69,11 @@ type Le struct {
 }
 
 func (le *Le) ToSQL() (string, []interface{}, error) {
-	return le.Field + " <= ?", []interface{}{le.Value}, nil
+	return writerToSQL(le)
+}
+
+func (le *Le) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, le.Field, " <= ", le.Value)
 }
 
 // Gt - Greater than: field > value
@@ -61,7 +83,11 @@ type Gt struct {
 }
 
 func (gt *Gt) ToSQL() (string, []interface{}, error) {
-	return gt.Field + " > ?", []interface{}{gt.Value}, nil
+	return writerToSQL(gt)
+}
+
+func (gt *Gt) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, gt.Field, " > ", gt.Value)
 }
 
 // Ge - Greater than or equal to: field >= value
@@ -71,20 +97,30 @@ type Ge struct {
 }
 
 func (ge *Ge) ToSQL() (string, []interface{}, error) {
-	return ge.Field + " >= ?", []interface{}{ge.Value}, nil
+	return writerToSQL(ge)
+}
+
+func (ge *Ge) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeCompare(buf, args, ge.Field, " >= ", ge.Value)
 }
 
 // In - Equals one value from set: field IN (value, value2)
+// if the only value is Sqlizer (e.g. *SelectBuilder) it is rendered as a subquery: field IN (SELECT ...),
+// the same applies to NotIn
 type In struct {
 	Field  string
 	Values []interface{}
 }
 
 func (in *In) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(in)
+}
+
+func (in *In) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
 	if len(in.Values) == 0 {
-		return "", nil, fmt.Errorf("'In' condition requires at least one value for field %s", in.Field)
+		return nil, fmt.Errorf("'In' condition requires at least one value for field %s", in.Field)
 	}
-	return in.Field + " IN (?" + strings.Repeat(",?", len(in.Values)-1) + ")", in.Values, nil
+	return writeIn(buf, args, in.Field, " IN ", in.Values)
 }
 
 // NotIn - Not in a set of values: field NOT IN (value, value2)
@@ -94,10 +130,14 @@ type NotIn struct {
 }
 
 func (n *NotIn) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(n)
+}
+
+func (n *NotIn) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
 	if len(n.Values) == 0 {
-		return "", nil, fmt.Errorf("'NotIn' condition requires at least one value for field %s", n.Field)
+		return nil, fmt.Errorf("'NotIn' condition requires at least one value for field %s", n.Field)
 	}
-	return n.Field + " NOT IN (?" + strings.Repeat(",?", len(n.Values)-1) + ")", n.Values, nil
+	return writeIn(buf, args, n.Field, " NOT IN ", n.Values)
 }
 
 // Like - contains text: field like value
@@ -110,6 +150,12 @@ func (l *Like) ToSQL() (string, []interface{}, error) {
 	return l.Field + " LIKE ?", []interface{}{l.Value}, nil
 }
 
+func (l *Like) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(l.Field)
+	buf.WriteString(" LIKE ?")
+	return append(args, l.Value), nil
+}
+
 // IsNull - Equal to null: field is null
 type IsNull string
 
@@ -117,6 +163,12 @@ func (i IsNull) ToSQL() (string, []interface{}, error) {
 	return string(i) + " IS NULL", nil, nil
 }
 
+func (i IsNull) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(string(i))
+	buf.WriteString(" IS NULL")
+	return args, nil
+}
+
 // IsNotNull - Not equal to null: field is not null
 type IsNotNull string
 
@@ -124,56 +176,38 @@ func (i IsNotNull) ToSQL() (string, []interface{}, error) {
 	return string(i) + " IS NOT NULL", nil, nil
 }
 
+func (i IsNotNull) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(string(i))
+	buf.WriteString(" IS NOT NULL")
+	return args, nil
+}
+
 // Or connects multiple expressions with the "OR" statement
 type Or []Sqlizer
 
-func (or Or) ToSQL() (sql string, args []interface{}, err error) {
+func (or Or) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(or)
+}
+
+func (or Or) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
 	if len(or) == 0 {
-		return "", nil, fmt.Errorf("'Or' requires at least one condition")
-	}
-	group := len(or) > 1
-	expr := new(bytes.Buffer)
-	args = make([]interface{}, 0)
-	if group {
-		expr.WriteByte('(')
-	}
-	args, err = appendToSQL(or, expr, " OR ", args)
-	if err != nil {
-		return
-	}
-	if group {
-		expr.WriteByte(')')
+		return nil, fmt.Errorf("'Or' requires at least one condition")
 	}
-	sql = expr.String()
-	return
+	return writeGroup(buf, args, or, " OR ")
 }
 
 // And connects multiple expressions with the "AND" statement
 type And []Sqlizer
 
-func (and And) ToSQL() (sql string, args []interface{}, err error) {
-	if len(and) == 0 {
-		return "", nil, fmt.Errorf("'And' requires at least one condition")
-	}
-
-	group := len(and) > 1
-	expr := new(bytes.Buffer)
-	args = make([]interface{}, 0)
-
-	if group {
-		expr.WriteByte('(')
-	}
-
-	args, err = appendToSQL(and, expr, " AND ", args)
-	if err != nil {
-		return "", nil, err
-	}
+func (and And) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(and)
+}
 
-	if group {
-		expr.WriteByte(')')
+func (and And) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	if len(and) == 0 {
+		return nil, fmt.Errorf("'And' requires at least one condition")
 	}
-
-	return expr.String(), args, nil
+	return writeGroup(buf, args, and, " AND ")
 }
 
 // RawSQLWithArgs is a free form sql with possible arguments
@@ -186,6 +220,23 @@ func (r *RawSQLWithArgs) ToSQL() (string, []interface{}, error) {
 	return r.SQL, r.Args, nil
 }
 
+func (r *RawSQLWithArgs) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(r.SQL)
+	return append(args, r.Args...), nil
+}
+
+// columnList is the list of the selected expressions joined by the comma,
+// it allows adding several expressions as a single column part
+type columnList []Sqlizer
+
+func (l columnList) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(l)
+}
+
+func (l columnList) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return appendToSQL(l, buf, ", ", args)
+}
+
 // Columns is a helper that simplifies columns list creation
 type Columns []string
 
@@ -201,6 +252,16 @@ func (s Columns) ToSQL() (string, []interface{}, error) {
 	return s.String(), nil, nil
 }
 
+func (s Columns) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	for i, column := range s {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(column)
+	}
+	return args, nil
+}
+
 // OrderBy is a helper that simplifies creation
 // of the "ORDER BY" SQL statement
 type OrderBy []qparser.Sort
@@ -227,17 +288,115 @@ func (s OrderBy) ToSQL() (string, []interface{}, error) {
 	return s.String(), nil, nil
 }
 
+func (s OrderBy) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	for i := 0; i < len(s); i++ {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(s[i].FieldName)
+		buf.WriteByte(' ')
+		buf.WriteString(s[i].Order.String())
+	}
+	return args, nil
+}
+
+// OrderByExpr is an "ORDER BY" item which sorts by the result of the expression
+type OrderByExpr struct {
+	Expr  Sqlizer
+	Order qparser.SortOrder
+}
+
+func (o *OrderByExpr) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(o)
+}
+
+func (o *OrderByExpr) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	args, err := WriteSQL(buf, args, o.Expr)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(o.Order.String())
+	return args, nil
+}
+
 // Not - Negates a single expression: NOT (expression)
 type Not struct {
 	Expr Sqlizer
 }
 
 func (n *Not) ToSQL() (string, []interface{}, error) {
-	sql, args, err := n.Expr.ToSQL()
+	return writerToSQL(n)
+}
+
+func (n *Not) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString("NOT ")
+	return (&SubQuery{Query: n.Expr}).WriteSQL(buf, args)
+}
+
+// SubQuery wraps the query in parentheses: (SELECT ...)
+type SubQuery struct {
+	Query Sqlizer
+}
+
+func (s *SubQuery) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(s)
+}
+
+func (s *SubQuery) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteByte('(')
+	args, err := WriteSQL(buf, args, s.Query)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte(')')
+	return args, nil
+}
+
+// Alias gives a name to the expression: expression AS name
+type Alias struct {
+	Expr Sqlizer
+	Name string
+}
+
+func (a *Alias) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(a)
+}
+
+func (a *Alias) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	args, err := WriteSQL(buf, args, a.Expr)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	return "NOT (" + sql + ")", args, nil
+	buf.WriteString(" AS ")
+	buf.WriteString(a.Name)
+	return args, nil
+}
+
+// Exists - subquery returns at least one row: EXISTS (SELECT ...)
+type Exists struct {
+	Query Sqlizer
+}
+
+func (e *Exists) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(e)
+}
+
+func (e *Exists) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeExists(buf, args, "EXISTS ", e.Query)
+}
+
+// NotExists - subquery returns no rows: NOT EXISTS (SELECT ...)
+type NotExists struct {
+	Query Sqlizer
+}
+
+func (n *NotExists) ToSQL() (string, []interface{}, error) {
+	return writerToSQL(n)
+}
+
+func (n *NotExists) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	return writeExists(buf, args, "NOT EXISTS ", n.Query)
 }
 
 // RawSQL is a raw SQL string without arguments
@@ -247,25 +406,83 @@ func (s RawSQL) ToSQL() (string, []interface{}, error) {
 	return string(s), nil, nil
 }
 
-func appendToSQL(parts []Sqlizer, w io.Writer, sep string, args []interface{}) ([]interface{}, error) {
-	for i, p := range parts {
-		partSQL, partArgs, err := p.ToSQL()
-		if err != nil {
-			return nil, err
-		}
-		if partSQL == "" {
-			continue
+func (s RawSQL) WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	buf.WriteString(string(s))
+	return args, nil
+}
+
+// writeCompare writes "field operator ?" or "field operator (subquery)" if the value is Sqlizer
+func writeCompare(buf *bytes.Buffer, args []interface{}, field, operator string, value interface{}) ([]interface{}, error) {
+	buf.WriteString(field)
+	buf.WriteString(operator)
+	if sub, ok := value.(Sqlizer); ok {
+		return (&SubQuery{Query: sub}).WriteSQL(buf, args)
+	}
+	buf.WriteByte('?')
+	return append(args, value), nil
+}
+
+// writeExists writes "operator (subquery)", the subquery is required
+func writeExists(buf *bytes.Buffer, args []interface{}, operator string, query Sqlizer) ([]interface{}, error) {
+	if query == nil {
+		return nil, ErrUndefinedSubquery
+	}
+	return writeCompare(buf, args, "", operator, query)
+}
+
+// writeIn writes "field IN (?,?)" or "field IN (subquery)" if the only value is Sqlizer
+func writeIn(buf *bytes.Buffer, args []interface{}, field, operator string, values []interface{}) ([]interface{}, error) {
+	if len(values) == 1 {
+		if sub, ok := values[0].(Sqlizer); ok {
+			return writeCompare(buf, args, field, operator, sub)
 		}
-		if i > 0 {
-			if _, inErr := io.WriteString(w, sep); inErr != nil {
-				return nil, inErr
-			}
+	}
+	buf.WriteString(field)
+	buf.WriteString(operator)
+	buf.WriteString("(?")
+	for i := 1; i < len(values); i++ {
+		buf.WriteString(",?")
+	}
+	buf.WriteByte(')')
+	return append(args, values...), nil
+}
+
+// writeGroup writes the parts joined by the separator, multiple parts are wrapped in parentheses
+func writeGroup(buf *bytes.Buffer, args []interface{}, parts []Sqlizer, sep string) ([]interface{}, error) {
+	group := len(parts) > 1
+	if group {
+		buf.WriteByte('(')
+	}
+	args, err := appendToSQL(parts, buf, sep, args)
+	if err != nil {
+		return nil, err
+	}
+	if group {
+		buf.WriteByte(')')
+	}
+	return args, nil
+}
+
+// appendToSQL writes the parts joined by the separator, the parts which render empty SQL are skipped
+func appendToSQL(parts []Sqlizer, buf *bytes.Buffer, sep string, args []interface{}) ([]interface{}, error) {
+	written := false
+	for _, p := range parts {
+		start := buf.Len()
+		if written {
+			buf.WriteString(sep)
 		}
-		_, err = io.WriteString(w, partSQL)
+		partStart, argsStart := buf.Len(), len(args)
+		var err error
+		args, err = WriteSQL(buf, args, p)
 		if err != nil {
 			return nil, err
 		}
-		args = append(args, partArgs...)
+		if buf.Len() == partStart {
+			buf.Truncate(start)
+			args = args[:argsStart]
+			continue
+		}
+		written = true
 	}
 	return args, nil
 }
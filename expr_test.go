@@ -210,6 +210,79 @@ var expressionTests = []expressionTest{
 		},
 		out: "field10 ASC, field11 DESC",
 	},
+	{
+		Name: "OrderByExpr",
+		in: &OrderByExpr{
+			Expr:  &RawSQLWithArgs{SQL: "ABS(field12 - ?)", Args: []interface{}{5}},
+			Order: qparser.OrderDesc,
+		},
+		out:  "ABS(field12 - ?) DESC",
+		args: []interface{}{5},
+	},
+	{
+		Name: "In operator with subquery",
+		in: &In{
+			Field: "id",
+			Values: []interface{}{
+				new(SelectBuilder).Select([]string{"article_id"}).From("tags").Where(&Eq{Field: "name", Value: "go"}),
+			},
+		},
+		out:  "id IN (SELECT article_id FROM tags WHERE name = ?)",
+		args: []interface{}{"go"},
+	},
+	{
+		Name: "NotIn operator with subquery",
+		in: &NotIn{
+			Field:  "id",
+			Values: []interface{}{&RawSQLWithArgs{SQL: "SELECT article_id FROM hidden WHERE user_id = ?", Args: []interface{}{7}}},
+		},
+		out:  "id NOT IN (SELECT article_id FROM hidden WHERE user_id = ?)",
+		args: []interface{}{7},
+	},
+	{
+		Name: "Eq operator with subquery",
+		in: &Eq{
+			Field: "price",
+			Value: new(SelectBuilder).Select([]string{"MAX(price)"}).From("products").Where(&Gt{Field: "stock", Value: 0}),
+		},
+		out:  "price = (SELECT MAX(price) FROM products WHERE stock > ?)",
+		args: []interface{}{0},
+	},
+	{
+		Name:      "Eq operator with invalid subquery",
+		in:        &Eq{Field: "price", Value: new(SelectBuilder)},
+		expectErr: true,
+	},
+	{
+		Name: "Exists",
+		in: &Exists{
+			Query: new(SelectBuilder).Select([]string{"1"}).From("comments").Where(RawSQL("comments.article_id = articles.id")),
+		},
+		out: "EXISTS (SELECT 1 FROM comments WHERE comments.article_id = articles.id)",
+	},
+	{
+		Name: "NotExists",
+		in: &NotExists{
+			Query: new(SelectBuilder).Select([]string{"1"}).From("comments").Where(&Eq{Field: "spam", Value: true}),
+		},
+		out:  "NOT EXISTS (SELECT 1 FROM comments WHERE spam = ?)",
+		args: []interface{}{true},
+	},
+	{
+		Name:      "Exists without query",
+		in:        &Exists{},
+		expectErr: true,
+	},
+	{
+		Name:      "NotExists without query",
+		in:        &NotExists{},
+		expectErr: true,
+	},
+	{
+		Name: "Alias",
+		in:   &Alias{Expr: RawSQL("COUNT(*)"), Name: "total"},
+		out:  "COUNT(*) AS total",
+	},
 }
 
 func TestExpressions(t *testing.T) {
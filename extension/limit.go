@@ -9,10 +9,17 @@ import (
 )
 
 // DefaultLimit is the extension that sets the query limit if it has not been set
-func DefaultLimit(limit uint64) q2sql.Extension {
+// it marks the builder page info with DefaultApplied flag
+func DefaultLimit(limit uint64, options ...PaginationOption) q2sql.Extension {
+	o := newPaginationOptions(options)
 	return func(_ context.Context, _ *qparser.Query, builder *q2sql.SelectBuilder) error {
 		if builder.LimitPart == "" {
-			builder.Limit(limit)
+			if builder.Page == nil {
+				builder.Page = new(q2sql.PageInfo)
+			}
+			builder.Page.Limit = limit
+			builder.Page.DefaultApplied = true
+			o.applyLimit(builder, builder.Page)
 		}
 		return nil
 	}
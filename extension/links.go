@@ -0,0 +1,129 @@
+package extension
+
+import (
+	"strconv"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+// UnknownTotal is used when the total number of records is not known
+const UnknownTotal = int64(-1)
+
+// Links contains canonical query strings (without the leading "?") of the pagination links.
+// Empty string means that the link is not available
+type Links struct {
+	Self  string `json:"self,omitempty"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// LinksOption configures the pagination links
+type LinksOption func(o *linksOptions)
+
+type linksOptions struct {
+	hasNext *bool
+}
+
+// HasNext tells whether the next page exists when the total is unknown,
+// e.g. the result of q2sql.TrimPage or q2sql.Executor with the FetchExtra option
+func HasNext(hasNext bool) LinksOption {
+	return func(o *linksOptions) {
+		o.hasNext = &hasNext
+	}
+}
+
+// LimitOffsetLinks creates links for the LimitOffsetPagination extension
+// based on the builder page info or the limit and offset set to the builder.
+// The total is the total number of records or UnknownTotal (any negative value),
+// if it is unknown the "last" link is not created and the "next" link is created
+// according to the HasNext option, it is always created without the option
+func LimitOffsetLinks(query *qparser.Query, builder *q2sql.SelectBuilder, total int64, options ...LinksOption) Links {
+	return pageLinks(query, builder, total, options, func(page *qparser.Page, limit, offset uint64) {
+		page.Limit = strconv.FormatUint(limit, 10)
+		page.Offset = strconv.FormatUint(offset, 10)
+		if offset == 0 {
+			page.Offset = ""
+		}
+	})
+}
+
+// LimitNumberLinks creates links for the LimitNumberPagination extension,
+// see LimitOffsetLinks for the details
+func LimitNumberLinks(query *qparser.Query, builder *q2sql.SelectBuilder, total int64, options ...LinksOption) Links {
+	return pageLinks(query, builder, total, options, func(page *qparser.Page, limit, offset uint64) {
+		page.Limit = strconv.FormatUint(limit, 10)
+		page.Number = strconv.FormatUint(offset/limit+1, 10)
+	})
+}
+
+// SizeNumberLinks creates links for the Pagination extension
+// which reads the "page[size]" and "page[number]" parameters, see LimitOffsetLinks for the details
+func SizeNumberLinks(query *qparser.Query, builder *q2sql.SelectBuilder, total int64, options ...LinksOption) Links {
+	return pageLinks(query, builder, total, options, func(page *qparser.Page, limit, offset uint64) {
+		page.Size = strconv.FormatUint(limit, 10)
+		page.Number = strconv.FormatUint(offset/limit+1, 10)
+	})
+}
+
+func pageLinks(
+	query *qparser.Query,
+	builder *q2sql.SelectBuilder,
+	total int64,
+	options []LinksOption,
+	setPage func(page *qparser.Page, limit, offset uint64),
+) Links {
+	o := new(linksOptions)
+	for _, option := range options {
+		option(o)
+	}
+	var limit, offset uint64
+	if builder.Page != nil {
+		limit, offset = builder.Page.Limit, builder.Page.Offset
+	} else {
+		limit, _ = strconv.ParseUint(builder.LimitPart, 10, 64)
+		offset, _ = strconv.ParseUint(builder.OffsetPart, 10, 64)
+	}
+	if limit == 0 {
+		self := q2sql.EncodeQuery(query)
+		return Links{Self: self, First: self}
+	}
+	link := func(offset uint64) string {
+		q := *query
+		q.Page = new(qparser.Page)
+		if query.Page != nil {
+			q.Page.Cursor = query.Page.Cursor
+		}
+		setPage(q.Page, limit, offset)
+		return q2sql.EncodeQuery(&q)
+	}
+	links := Links{
+		Self:  link(offset),
+		First: link(0),
+	}
+	if offset > 0 {
+		prev := uint64(0)
+		if offset > limit {
+			prev = offset - limit
+		}
+		links.Prev = link(prev)
+	}
+	hasNext := total < 0 || offset+limit < uint64(total)
+	if total < 0 && o.hasNext != nil {
+		hasNext = *o.hasNext
+	}
+	if hasNext {
+		links.Next = link(offset + limit)
+	}
+	if total >= 0 {
+		last := uint64(0)
+		if total > 0 {
+			last = (uint64(total) - 1) / limit * limit
+		}
+		links.Last = link(last)
+	}
+	return links
+}
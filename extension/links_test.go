@@ -0,0 +1,159 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+type linksTest struct {
+	ext     q2sql.Extension
+	links   func(*qparser.Query, *q2sql.SelectBuilder, int64, ...LinksOption) Links
+	query   string
+	total   int64
+	options []LinksOption
+	want    Links
+}
+
+var linksTests = []linksTest{
+	{
+		ext:   LimitOffsetPagination(Unlimited, Unlimited),
+		links: LimitOffsetLinks,
+		query: "filter[id]=gt:1&page[limit]=10&page[offset]=15",
+		total: 42,
+		want: Links{
+			Self:  "filter[id]=gt:1&page[limit]=10&page[offset]=15",
+			First: "filter[id]=gt:1&page[limit]=10",
+			Prev:  "filter[id]=gt:1&page[limit]=10&page[offset]=5",
+			Next:  "filter[id]=gt:1&page[limit]=10&page[offset]=25",
+			Last:  "filter[id]=gt:1&page[limit]=10&page[offset]=40",
+		},
+	},
+	{
+		ext:   LimitOffsetPagination(Unlimited, Unlimited),
+		links: LimitOffsetLinks,
+		query: "page[limit]=10&page[offset]=40",
+		total: 42,
+		want: Links{
+			Self:  "page[limit]=10&page[offset]=40",
+			First: "page[limit]=10",
+			Prev:  "page[limit]=10&page[offset]=30",
+			Last:  "page[limit]=10&page[offset]=40",
+		},
+	},
+	{
+		ext:   LimitOffsetPagination(Unlimited, Unlimited),
+		links: LimitOffsetLinks,
+		query: "page[limit]=10",
+		total: UnknownTotal,
+		want: Links{
+			Self:  "page[limit]=10",
+			First: "page[limit]=10",
+			Next:  "page[limit]=10&page[offset]=10",
+		},
+	},
+	{
+		ext:     LimitOffsetPagination(Unlimited, Unlimited, FetchExtra()),
+		links:   LimitOffsetLinks,
+		query:   "page[limit]=10&page[offset]=10",
+		total:   UnknownTotal,
+		options: []LinksOption{HasNext(false)},
+		want: Links{
+			Self:  "page[limit]=10&page[offset]=10",
+			First: "page[limit]=10",
+			Prev:  "page[limit]=10",
+		},
+	},
+	{
+		ext:     LimitOffsetPagination(Unlimited, Unlimited, FetchExtra()),
+		links:   LimitOffsetLinks,
+		query:   "page[limit]=10",
+		total:   UnknownTotal,
+		options: []LinksOption{HasNext(true)},
+		want: Links{
+			Self:  "page[limit]=10",
+			First: "page[limit]=10",
+			Next:  "page[limit]=10&page[offset]=10",
+		},
+	},
+	{
+		ext:     LimitOffsetPagination(Unlimited, Unlimited),
+		links:   LimitOffsetLinks,
+		query:   "page[limit]=10",
+		total:   5,
+		options: []LinksOption{HasNext(true)},
+		want: Links{
+			Self:  "page[limit]=10",
+			First: "page[limit]=10",
+			Last:  "page[limit]=10",
+		},
+	},
+	{
+		ext:   DefaultLimit(20),
+		links: LimitOffsetLinks,
+		query: "sort=-id",
+		total: 0,
+		want: Links{
+			Self:  "sort=-id&page[limit]=20",
+			First: "sort=-id&page[limit]=20",
+			Last:  "sort=-id&page[limit]=20",
+		},
+	},
+	{
+		ext:   LimitOffsetPagination(Unlimited, Unlimited),
+		links: LimitOffsetLinks,
+		query: "sort=-id",
+		total: 100,
+		want: Links{
+			Self:  "sort=-id",
+			First: "sort=-id",
+		},
+	},
+	{
+		ext:   LimitNumberPagination(Unlimited),
+		links: LimitNumberLinks,
+		query: "page[limit]=10&page[number]=2",
+		total: 30,
+		want: Links{
+			Self:  "page[limit]=10&page[number]=2",
+			First: "page[limit]=10&page[number]=1",
+			Prev:  "page[limit]=10&page[number]=1",
+			Next:  "page[limit]=10&page[number]=3",
+			Last:  "page[limit]=10&page[number]=3",
+		},
+	},
+	{
+		ext:   DefaultLimit(5),
+		links: SizeNumberLinks,
+		query: "",
+		total: 6,
+		want: Links{
+			Self:  "page[number]=1&page[size]=5",
+			First: "page[number]=1&page[size]=5",
+			Next:  "page[number]=2&page[size]=5",
+			Last:  "page[number]=2&page[size]=5",
+		},
+	},
+}
+
+func TestLinks(t *testing.T) {
+	ctx := context.Background()
+	for i, tt := range linksTests {
+		meta := fmt.Sprintf("test %d (%q)", i, tt.query)
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", meta, err)
+		}
+		b := new(q2sql.SelectBuilder)
+		if err = tt.ext(ctx, query, b); err != nil {
+			t.Fatalf("%s: unexpected error %s", meta, err)
+		}
+		if got := tt.links(query, b, tt.total, tt.options...); got != tt.want {
+			t.Errorf("%s:\n\tgot  %+v\n\twant %+v", meta, got, tt.want)
+		}
+	}
+}
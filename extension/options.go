@@ -0,0 +1,40 @@
+package extension
+
+import "github.com/velmie/q2sql"
+
+// PaginationOption configures pagination extensions
+type PaginationOption func(o *paginationOptions)
+
+type paginationOptions struct {
+	fetchExtra bool
+}
+
+// FetchExtra makes pagination extensions request one record more than the page size,
+// so it is possible to detect if the next page exists without counting records.
+//
+// The SQL LIMIT is the page size + 1 whereas the builder page info contains
+// the requested page size and the FetchExtra flag.
+// Use q2sql.TrimPage or q2sql.Executor in order to drop the extra record
+func FetchExtra() PaginationOption {
+	return func(o *paginationOptions) {
+		o.fetchExtra = true
+	}
+}
+
+func newPaginationOptions(options []PaginationOption) *paginationOptions {
+	o := new(paginationOptions)
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// applyLimit sets the builder limit taking into account the FetchExtra option
+func (o *paginationOptions) applyLimit(builder *q2sql.SelectBuilder, info *q2sql.PageInfo) {
+	if o.fetchExtra {
+		info.FetchExtra = true
+		builder.Limit(info.Limit + 1)
+		return
+	}
+	builder.Limit(info.Limit)
+}
@@ -19,13 +19,45 @@ type LimitOffsetPaginationParams struct {
 	OffsetParameterName string
 }
 
+// WithLimitOffsetPagination adds LimitOffsetPagination configured by the params to the builder
+// and documents its "page[limit]" and "page[offset]" parameters with the same maximums,
+// see q2sql.ExtendPagination
+func WithLimitOffsetPagination(params LimitOffsetPaginationParams, options ...PaginationOption) q2sql.ResourceSelectBuilderOption {
+	return q2sql.ExtendPagination(
+		LimitOffsetPagination(params.MaxLimit, params.MaxOffset, options...),
+		q2sql.PageParameter{Name: "limit", Type: q2sql.FieldTypeInteger, Maximum: documentedMaximum(params.MaxLimit)},
+		q2sql.PageParameter{Name: "offset", Type: q2sql.FieldTypeInteger, Maximum: documentedMaximum(params.MaxOffset)},
+	)
+}
+
+// WithLimitNumberPagination adds LimitNumberPagination to the builder
+// and documents its "page[limit]" and "page[number]" parameters, see q2sql.ExtendPagination
+func WithLimitNumberPagination(maxLimit int64, options ...PaginationOption) q2sql.ResourceSelectBuilderOption {
+	return q2sql.ExtendPagination(
+		LimitNumberPagination(maxLimit, options...),
+		q2sql.PageParameter{Name: "limit", Type: q2sql.FieldTypeInteger, Maximum: documentedMaximum(maxLimit)},
+		q2sql.PageParameter{Name: "number", Type: q2sql.FieldTypeInteger},
+	)
+}
+
+// documentedMaximum converts Unlimited to zero which means there is no maximum
+func documentedMaximum(maxValue int64) int64 {
+	if maxValue == Unlimited {
+		return 0
+	}
+	return maxValue
+}
+
 // LimitOffsetPagination is the extension
 // that sets limit and offset based on the corresponding fields of the given query.Page
+// and records them to the builder page info
 //
 //nolint:gocognit // skip because it is covered by tests
-func LimitOffsetPagination(maxLimit, maxOffset int64) q2sql.Extension {
+func LimitOffsetPagination(maxLimit, maxOffset int64, options ...PaginationOption) q2sql.Extension {
+	o := newPaginationOptions(options)
 	return func(_ context.Context, query *qparser.Query, builder *q2sql.SelectBuilder) error {
 		if page := query.Page; page != nil {
+			info := &q2sql.PageInfo{Cursor: page.Cursor}
 			if page.Limit != "" {
 				limit, err := strconv.ParseUint(page.Limit, 10, 32)
 				if err != nil {
@@ -34,7 +66,8 @@ func LimitOffsetPagination(maxLimit, maxOffset int64) q2sql.Extension {
 				if maxLimit != Unlimited && int64(limit) > maxLimit {
 					return fmt.Errorf("page limit cannot be greater than %d", maxLimit)
 				}
-				builder.Limit(limit)
+				info.Limit = limit
+				o.applyLimit(builder, info)
 			}
 			if page.Offset != "" && page.Limit == "" {
 				return fmt.Errorf("offset cannot be used without specifying limit")
@@ -48,6 +81,10 @@ func LimitOffsetPagination(maxLimit, maxOffset int64) q2sql.Extension {
 					return fmt.Errorf("page offset cannot be greater than %d", maxOffset)
 				}
 				builder.Offset(offset)
+				info.Offset = offset
+			}
+			if page.Limit != "" {
+				builder.Page = info
 			}
 		}
 		return nil
@@ -57,7 +94,7 @@ func LimitOffsetPagination(maxLimit, maxOffset int64) q2sql.Extension {
 // LimitNumberPagination is the extension
 // that sets limit and offset based on the corresponding fields of the given query.Page
 // where offset is a result of the expression limit * (number - 1)
-func LimitNumberPagination(maxLimit int64) q2sql.Extension {
+func LimitNumberPagination(maxLimit int64, options ...PaginationOption) q2sql.Extension {
 	return Pagination(
 		maxLimit,
 		func(query *qparser.Query) (limit uint64, number uint64, err error) {
@@ -77,16 +114,21 @@ func LimitNumberPagination(maxLimit int64) q2sql.Extension {
 				return 0, 0, fmt.Errorf("page number must be unsigned integer, got %q", page.Number)
 			}
 			return limit, number, nil
-		})
+		},
+		options...,
+	)
 }
 
 // Pagination is the extension
 // that sets limit and offset based on the size and number values returned by the "sizeAndNumberGetter"
-// argument. Offset is a result of the expression size * (number - 1)
+// argument. Offset is a result of the expression size * (number - 1).
+// The size, offset and number are recorded to the builder page info
 func Pagination(
 	maxLimit int64,
 	sizeAndNumberGetter func(*qparser.Query) (size uint64, number uint64, err error),
+	options ...PaginationOption,
 ) q2sql.Extension {
+	o := newPaginationOptions(options)
 	return func(_ context.Context, query *qparser.Query, builder *q2sql.SelectBuilder) error {
 		size, number, err := sizeAndNumberGetter(query)
 		if err != nil {
@@ -100,11 +142,18 @@ func Pagination(
 		if maxLimit != Unlimited && int64(size) > maxLimit {
 			return fmt.Errorf("page limit cannot be greater than %d", maxLimit)
 		}
-		builder.Limit(size)
+		info := &q2sql.PageInfo{Limit: size, Number: 1}
+		o.applyLimit(builder, info)
+		if query.Page != nil {
+			info.Cursor = query.Page.Cursor
+		}
 
 		if number > 1 {
-			builder.Offset(size * (number - 1))
+			info.Offset = size * (number - 1)
+			info.Number = number
+			builder.Offset(info.Offset)
 		}
+		builder.Page = info
 		return nil
 	}
 }
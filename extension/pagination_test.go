@@ -3,6 +3,7 @@ package extension
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -145,3 +146,161 @@ func TestLimitNumberPagination(t *testing.T) {
 		}
 	}
 }
+
+type pageInfoTest struct {
+	p    q2sql.Extension
+	q    *qparser.Query
+	info *q2sql.PageInfo
+}
+
+var pageInfoTests = []pageInfoTest{
+	{
+		p:    LimitOffsetPagination(Unlimited, Unlimited),
+		q:    &qparser.Query{Page: &qparser.Page{Limit: "10", Offset: "30", Cursor: "abc"}},
+		info: &q2sql.PageInfo{Limit: 10, Offset: 30, Cursor: "abc"},
+	},
+	{
+		p:    LimitOffsetPagination(Unlimited, Unlimited),
+		q:    &qparser.Query{Page: &qparser.Page{Cursor: "abc"}},
+		info: nil,
+	},
+	{
+		p:    LimitNumberPagination(Unlimited),
+		q:    &qparser.Query{Page: &qparser.Page{Limit: "10", Number: "4"}},
+		info: &q2sql.PageInfo{Limit: 10, Offset: 30, Number: 4},
+	},
+	{
+		p:    LimitNumberPagination(Unlimited),
+		q:    &qparser.Query{Page: &qparser.Page{Limit: "10", Number: "0"}},
+		info: &q2sql.PageInfo{Limit: 10, Number: 1},
+	},
+	{
+		p:    DefaultLimit(25),
+		q:    new(qparser.Query),
+		info: &q2sql.PageInfo{Limit: 25, DefaultApplied: true},
+	},
+}
+
+func TestPaginationPageInfo(t *testing.T) {
+	ctx := context.Background()
+	for i, tt := range pageInfoTests {
+		meta := fmt.Sprintf("test %d", i)
+		b := new(q2sql.SelectBuilder)
+		if err := tt.p(ctx, tt.q, b); err != nil {
+			t.Errorf("%s, unexpected error %s", meta, err)
+			continue
+		}
+		if !reflect.DeepEqual(b.Page, tt.info) {
+			t.Errorf("%s, unexpected page info, want %+v, got %+v", meta, tt.info, b.Page)
+		}
+	}
+}
+
+type fetchExtraTest struct {
+	p         q2sql.Extension
+	q         *qparser.Query
+	limitPart string
+	info      *q2sql.PageInfo
+}
+
+var fetchExtraTests = []fetchExtraTest{
+	{
+		p:         LimitOffsetPagination(Unlimited, Unlimited, FetchExtra()),
+		q:         &qparser.Query{Page: &qparser.Page{Limit: "10", Offset: "20"}},
+		limitPart: "11",
+		info:      &q2sql.PageInfo{Limit: 10, Offset: 20, FetchExtra: true},
+	},
+	{
+		p:         LimitOffsetPagination(10, Unlimited, FetchExtra()),
+		q:         &qparser.Query{Page: &qparser.Page{Limit: "10"}},
+		limitPart: "11",
+		info:      &q2sql.PageInfo{Limit: 10, FetchExtra: true},
+	},
+	{
+		p:         LimitNumberPagination(Unlimited, FetchExtra()),
+		q:         &qparser.Query{Page: &qparser.Page{Limit: "10", Number: "3"}},
+		limitPart: "11",
+		info:      &q2sql.PageInfo{Limit: 10, Offset: 20, Number: 3, FetchExtra: true},
+	},
+	{
+		p:         DefaultLimit(50, FetchExtra()),
+		q:         new(qparser.Query),
+		limitPart: "51",
+		info:      &q2sql.PageInfo{Limit: 50, DefaultApplied: true, FetchExtra: true},
+	},
+}
+
+func TestFetchExtra(t *testing.T) {
+	ctx := context.Background()
+	for i, tt := range fetchExtraTests {
+		meta := fmt.Sprintf("test %d", i)
+		b := new(q2sql.SelectBuilder)
+		if err := tt.p(ctx, tt.q, b); err != nil {
+			t.Errorf("%s, unexpected error %s", meta, err)
+			continue
+		}
+		// the SQL limit is greater than the requested page size by one record
+		if b.LimitPart != tt.limitPart {
+			t.Errorf("%s, unexpected limit part, want %s, got %s", meta, tt.limitPart, b.LimitPart)
+		}
+		if !reflect.DeepEqual(b.Page, tt.info) {
+			t.Errorf("%s, unexpected page info, want %+v, got %+v", meta, tt.info, b.Page)
+		}
+	}
+}
+
+func TestPaginationOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   q2sql.ResourceSelectBuilderOption
+		want     []q2sql.PageParameter
+		accepted string
+		rejected string
+	}{
+		{
+			name:   "limit and offset",
+			option: WithLimitOffsetPagination(LimitOffsetPaginationParams{MaxLimit: 100, MaxOffset: Unlimited}),
+			want: []q2sql.PageParameter{
+				{Name: "limit", Type: q2sql.FieldTypeInteger, Maximum: 100},
+				{Name: "offset", Type: q2sql.FieldTypeInteger},
+			},
+			accepted: "page[limit]=100&page[offset]=100000",
+			rejected: "page[limit]=101",
+		},
+		{
+			name:   "limit and number",
+			option: WithLimitNumberPagination(50),
+			want: []q2sql.PageParameter{
+				{Name: "limit", Type: q2sql.FieldTypeInteger, Maximum: 50},
+				{Name: "number", Type: q2sql.FieldTypeInteger},
+			},
+			accepted: "page[limit]=50&page[number]=3",
+			rejected: "page[limit]=51&page[number]=1",
+		},
+	}
+	for _, tt := range tests {
+		builder := q2sql.NewResourceSelectBuilder(
+			"articles",
+			q2sql.MapTranslator(map[string]string{"id": "id"}),
+			q2sql.WithDefaultFields([]string{"id"}),
+			tt.option,
+		)
+		if got := builder.Describe().Pagination; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+		query, err := qparser.ParseQuery(tt.accepted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = builder.Build(context.Background(), query); err != nil {
+			t.Errorf("%s: unexpected error %s", tt.name, err)
+		}
+		query, err = qparser.ParseQuery(tt.rejected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = builder.Build(context.Background(), query); err == nil {
+			t.Errorf("%s: expected the documented maximum to be checked", tt.name)
+		}
+	}
+}
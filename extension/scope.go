@@ -0,0 +1,81 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+// ErrScopeValueMissing is returned when the scope value cannot be retrieved from the context
+const ErrScopeValueMissing = q2sql.Error("scope value is missing in the context")
+
+// ScopeValue retrieves the scope value from the context,
+// the second return value indicates if the value is found
+type ScopeValue func(ctx context.Context) (value interface{}, ok bool)
+
+// ContextValue creates ScopeValue which retrieves the value stored in the context by the key.
+// Nil value is considered missing
+func ContextValue(key interface{}) ScopeValue {
+	return func(ctx context.Context) (interface{}, bool) {
+		value := ctx.Value(key)
+		return value, value != nil
+	}
+}
+
+// Scope is the extension that adds the mandatory "column = value" condition
+// where the value is retrieved from the context, for example tenant identifier
+// of the authenticated principal.
+//
+// The extension fails closed: ErrScopeValueMissing is returned if the value is missing.
+// Scope conditions are placed first and all other conditions are grouped in parentheses,
+// so the scope cannot be bypassed by the conditions containing "OR"
+func Scope(column string, value ScopeValue) q2sql.Extension {
+	return func(ctx context.Context, _ *qparser.Query, builder *q2sql.SelectBuilder) error {
+		v, ok := value(ctx)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrScopeValueMissing, column)
+		}
+		var (
+			scopes []q2sql.Sqlizer
+			rest   group
+		)
+		for _, part := range builder.WhereParts {
+			if sc, ok := part.(*scopeCondition); ok {
+				scopes = append(scopes, sc)
+				continue
+			}
+			if g, ok := part.(group); ok {
+				rest = append(rest, g...)
+				continue
+			}
+			rest = append(rest, part)
+		}
+		scopes = append(scopes, &scopeCondition{q2sql.Eq{Field: column, Value: v}})
+		builder.WhereParts = scopes
+		if len(rest) > 0 {
+			builder.WhereParts = append(builder.WhereParts, rest)
+		}
+		return nil
+	}
+}
+
+type scopeCondition struct {
+	q2sql.Eq
+}
+
+// group joins conditions with "AND" and always wraps them in parentheses
+type group []q2sql.Sqlizer
+
+func (g group) ToSQL() (string, []interface{}, error) {
+	sql, args, err := q2sql.And(g).ToSQL()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(g) == 1 {
+		sql = "(" + sql + ")"
+	}
+	return sql, args, nil
+}
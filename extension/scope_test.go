@@ -0,0 +1,126 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+type scopeKey string
+
+func TestScope(t *testing.T) {
+	const (
+		tenantKey = scopeKey("tenant")
+		orgKey    = scopeKey("org")
+	)
+	ctx := context.WithValue(context.Background(), tenantKey, 42)
+	ctx = context.WithValue(ctx, orgKey, "acme")
+
+	b := new(q2sql.SelectBuilder).
+		Select([]string{"id"}).
+		From("articles").
+		Where(q2sql.RawSQL("status = 'draft' OR 1 = 1")).
+		Where(&q2sql.Eq{Field: "author", Value: "x"})
+
+	for _, ext := range []q2sql.Extension{
+		Scope("tenant_id", ContextValue(tenantKey)),
+		Scope("org_id", ContextValue(orgKey)),
+	} {
+		if err := ext(ctx, new(qparser.Query), b); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+	}
+	sql, args, err := b.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "SELECT id FROM articles WHERE tenant_id = ? AND org_id = ? AND (status = 'draft' OR 1 = 1 AND author = ?)"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q\n\tgot %q", expectedSQL, sql)
+	}
+	expectedArgs := []interface{}{42, "acme", "x"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %+v, got %+v", expectedArgs, args)
+	}
+}
+
+func TestScopeSingleCondition(t *testing.T) {
+	const tenantKey = scopeKey("tenant")
+	ctx := context.WithValue(context.Background(), tenantKey, 1)
+	b := new(q2sql.SelectBuilder).Select([]string{"id"}).From("articles").Where(q2sql.RawSQL("a = 1 OR b = 2"))
+	if err := Scope("tenant_id", ContextValue(tenantKey))(ctx, new(qparser.Query), b); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, _, err := b.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "SELECT id FROM articles WHERE tenant_id = ? AND (a = 1 OR b = 2)"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q\n\tgot %q", expectedSQL, sql)
+	}
+}
+
+func TestScopeValueMissing(t *testing.T) {
+	b := new(q2sql.SelectBuilder)
+	err := Scope("tenant_id", ContextValue(scopeKey("tenant")))(context.Background(), new(qparser.Query), b)
+	if !errors.Is(err, ErrScopeValueMissing) {
+		t.Errorf("expected ErrScopeValueMissing, got %v", err)
+	}
+	if len(b.WhereParts) != 0 {
+		t.Error("where parts must not be changed")
+	}
+}
+
+func TestScopeUnfilteredDelete(t *testing.T) {
+	const tenantKey = scopeKey("tenant")
+	ctx := context.WithValue(context.Background(), tenantKey, 42)
+	builder := q2sql.NewResourceSelectBuilder(
+		"articles",
+		q2sql.MapTranslator(map[string]string{"id": "id", "status": "status"}),
+		q2sql.AllowFiltering(
+			q2sql.AllowedConditions{"status": []string{"eq"}},
+			q2sql.ConditionMap{
+				"eq": func(field string, args ...interface{}) (q2sql.Sqlizer, error) {
+					return &q2sql.Eq{Field: field, Value: args[0]}, nil
+				},
+			},
+			q2sql.DefaultFilterExpressionParser,
+		),
+		q2sql.Extend(Scope("tenant_id", ContextValue(tenantKey))),
+	)
+
+	d, err := builder.BuildDelete(ctx, new(qparser.Query))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, _, err = d.ToSQL(); !errors.Is(err, q2sql.ErrUnfiltered) {
+		t.Errorf("expected ErrUnfiltered, got %v", err)
+	}
+
+	query, err := qparser.ParseQuery("filter[status]=eq:spam")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	d, err = builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err := d.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "DELETE FROM articles WHERE tenant_id = ? AND (status = ?)"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q\n\tgot %q", expectedSQL, sql)
+	}
+	expectedArgs := []interface{}{42, "spam"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %+v, got %+v", expectedArgs, args)
+	}
+}
@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"strconv"
+
+	"github.com/velmie/q2sql"
+)
+
+// WGS84 is the spatial reference identifier of the World Geodetic System
+const WGS84 = 4326
+
+// PostGIS renders expressions for PostgreSQL with the PostGIS extension,
+// the column is expected to be a geometry or geography with the WGS84 SRID
+var PostGIS Dialect = NewPostGIS(WGS84)
+
+// MySQL renders expressions for MySQL spatial, the column is expected
+// to be a point whose X is longitude and Y is latitude
+var MySQL Dialect = mySQL{}
+
+// NewPostGIS creates PostGIS dialect with the given spatial reference identifier
+func NewPostGIS(srid int) Dialect {
+	return postGIS{srid: strconv.Itoa(srid)}
+}
+
+type postGIS struct {
+	srid string
+}
+
+func (d postGIS) point() string {
+	return "ST_SetSRID(ST_MakePoint(?, ?), " + d.srid + ")::geography"
+}
+
+func (d postGIS) Near(column string, p Point, radius float64) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  "ST_DWithin(" + column + "::geography, " + d.point() + ", ?)",
+		Args: []interface{}{p.Lng, p.Lat, radius},
+	}
+}
+
+func (d postGIS) Within(column string, b Box) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  column + "::geometry && ST_MakeEnvelope(?, ?, ?, ?, " + d.srid + ")",
+		Args: []interface{}{b.Min.Lng, b.Min.Lat, b.Max.Lng, b.Max.Lat},
+	}
+}
+
+func (d postGIS) Distance(column string, p Point) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  "ST_Distance(" + column + "::geography, " + d.point() + ")",
+		Args: []interface{}{p.Lng, p.Lat},
+	}
+}
+
+type mySQL struct{}
+
+func (mySQL) Near(column string, p Point, radius float64) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  "ST_Distance_Sphere(" + column + ", POINT(?, ?)) <= ?",
+		Args: []interface{}{p.Lng, p.Lat, radius},
+	}
+}
+
+func (mySQL) Within(column string, b Box) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  "MBRContains(ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)), " + column + ")",
+		Args: []interface{}{b.Min.Lng, b.Min.Lat, b.Max.Lng, b.Max.Lat},
+	}
+}
+
+func (mySQL) Distance(column string, p Point) q2sql.Sqlizer {
+	return &q2sql.RawSQLWithArgs{
+		SQL:  "ST_Distance_Sphere(" + column + ", POINT(?, ?))",
+		Args: []interface{}{p.Lng, p.Lat},
+	}
+}
@@ -0,0 +1,189 @@
+// Package geo provides geospatial filtering conditions and sorting by distance
+package geo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+// These constants are recommended names for the filters
+const (
+	NameNear   = "near"
+	NameWithin = "within"
+)
+
+// ErrInvalidArgument is returned when geospatial condition arguments are not valid
+const ErrInvalidArgument = q2sql.Error("invalid geospatial argument")
+
+const (
+	maxLatitude  = 90
+	maxLongitude = 180
+)
+
+// Point is a geographic point, coordinates are in degrees
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Validate checks that the point coordinates are within the valid ranges
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -maxLatitude || p.Lat > maxLatitude {
+		return fmt.Errorf("%w: latitude must be within [-90, 90], got %v", ErrInvalidArgument, p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -maxLongitude || p.Lng > maxLongitude {
+		return fmt.Errorf("%w: longitude must be within [-180, 180], got %v", ErrInvalidArgument, p.Lng)
+	}
+	return nil
+}
+
+// Box is a bounding box defined by the south-west (Min) and north-east (Max) corners
+type Box struct {
+	Min Point
+	Max Point
+}
+
+// Validate checks that the box corners are valid and ordered
+func (b Box) Validate() error {
+	if err := b.Min.Validate(); err != nil {
+		return err
+	}
+	if err := b.Max.Validate(); err != nil {
+		return err
+	}
+	if b.Min.Lat > b.Max.Lat || b.Min.Lng > b.Max.Lng {
+		return fmt.Errorf("%w: minimal coordinates must not be greater than maximal ones", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Dialect renders geospatial expressions for the specific database
+type Dialect interface {
+	// Near creates expression which is true if the column is within the radius (in meters) of the point
+	Near(column string, p Point, radius float64) q2sql.Sqlizer
+	// Within creates expression which is true if the column is within the bounding box
+	Within(column string, b Box) q2sql.Sqlizer
+	// Distance creates expression which calculates distance in meters between the column and the point
+	Distance(column string, p Point) q2sql.Sqlizer
+}
+
+// Near creates condition which expects latitude, longitude and radius in meters
+//
+// "filter[location]=near:53.9,27.56,1000"
+func Near(d Dialect) q2sql.Condition {
+	return func(field string, args ...interface{}) (q2sql.Sqlizer, error) {
+		const argsCount = 3
+		values, err := floatArgs(args, argsCount)
+		if err != nil {
+			return nil, err
+		}
+		p := Point{Lat: values[0], Lng: values[1]}
+		if err = p.Validate(); err != nil {
+			return nil, err
+		}
+		radius := values[2]
+		if math.IsNaN(radius) || math.IsInf(radius, 0) || radius <= 0 {
+			return nil, fmt.Errorf("%w: radius must be positive number, got %v", ErrInvalidArgument, radius)
+		}
+		return d.Near(field, p, radius), nil
+	}
+}
+
+// Within creates condition which expects minimal latitude, minimal longitude,
+// maximal latitude and maximal longitude of the bounding box
+//
+// "filter[location]=within:53.8,27.4,54,27.7"
+func Within(d Dialect) q2sql.Condition {
+	return func(field string, args ...interface{}) (q2sql.Sqlizer, error) {
+		const argsCount = 4
+		values, err := floatArgs(args, argsCount)
+		if err != nil {
+			return nil, err
+		}
+		b := Box{
+			Min: Point{Lat: values[0], Lng: values[1]},
+			Max: Point{Lat: values[2], Lng: values[3]},
+		}
+		if err = b.Validate(); err != nil {
+			return nil, err
+		}
+		return d.Within(field, b), nil
+	}
+}
+
+// Conditions returns map of the geospatial conditions with recommended names
+func Conditions(d Dialect) q2sql.ConditionMap {
+	return q2sql.ConditionMap{
+		NameNear:   Near(d),
+		NameWithin: Within(d),
+	}
+}
+
+// DistanceSort creates sort expression which orders by the distance between
+// the column and the point given in the query parameter as "latitude,longitude"
+//
+//	q2sql.AllowSortingByExpressions(map[string]q2sql.SortExpression{
+//		"distance": geo.DistanceSort(geo.PostGIS, "location", "origin"),
+//	})
+//
+// "sort=distance&origin=53.9,27.56"
+func DistanceSort(d Dialect, column, pointParameter string) q2sql.SortExpression {
+	return func(_ context.Context, query *qparser.Query) (q2sql.Sqlizer, error) {
+		value, ok := query.Values.GetExist(pointParameter)
+		if !ok {
+			return nil, fmt.Errorf("sorting by distance requires the %q parameter", pointParameter)
+		}
+		p, err := ParsePoint(value)
+		if err != nil {
+			return nil, err
+		}
+		return d.Distance(column, p), nil
+	}
+}
+
+// ParsePoint parses point given as "latitude,longitude"
+func ParsePoint(s string) (Point, error) {
+	parts := strings.Split(s, ",")
+	args := make([]interface{}, len(parts))
+	for i, part := range parts {
+		args[i] = part
+	}
+	const argsCount = 2
+	values, err := floatArgs(args, argsCount)
+	if err != nil {
+		return Point{}, err
+	}
+	p := Point{Lat: values[0], Lng: values[1]}
+	return p, p.Validate()
+}
+
+func floatArgs(args []interface{}, count int) ([]float64, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("%w: %d arguments are expected, got %d", ErrInvalidArgument, count, len(args))
+	}
+	values := make([]float64, count)
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case float64:
+			values[i] = v
+		case int:
+			values[i] = float64(v)
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: number is expected, got %q", ErrInvalidArgument, v)
+			}
+			values[i] = f
+		default:
+			return nil, fmt.Errorf("%w: number is expected, got %T", ErrInvalidArgument, arg)
+		}
+	}
+	return values, nil
+}
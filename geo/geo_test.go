@@ -0,0 +1,134 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+
+	"github.com/velmie/q2sql"
+)
+
+type conditionTest struct {
+	condition q2sql.Condition
+	args      []interface{}
+	sql       string
+	sqlArgs   []interface{}
+	expectErr bool
+}
+
+var conditionTests = []conditionTest{
+	{
+		condition: Near(PostGIS),
+		args:      []interface{}{"53.9", "27.56", "1000"},
+		sql:       "ST_DWithin(location::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)",
+		sqlArgs:   []interface{}{27.56, 53.9, 1000.0},
+	},
+	{
+		condition: Near(MySQL),
+		args:      []interface{}{53.9, 27.56, 1000},
+		sql:       "ST_Distance_Sphere(location, POINT(?, ?)) <= ?",
+		sqlArgs:   []interface{}{27.56, 53.9, 1000.0},
+	},
+	{
+		condition: Within(PostGIS),
+		args:      []interface{}{"53.8", "27.4", "54", "27.7"},
+		sql:       "location::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)",
+		sqlArgs:   []interface{}{27.4, 53.8, 27.7, 54.0},
+	},
+	{
+		condition: Within(MySQL),
+		args:      []interface{}{"53.8", "27.4", "54", "27.7"},
+		sql:       "MBRContains(ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)), location)",
+		sqlArgs:   []interface{}{27.4, 53.8, 27.7, 54.0},
+	},
+	{condition: Near(PostGIS), args: []interface{}{"53.9", "27.56"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{"91", "27.56", "10"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{"53.9", "-181", "10"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{"53.9", "27.56", "0"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{"53.9", "27.56", "NaN"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{"north", "27.56", "10"}, expectErr: true},
+	{condition: Near(PostGIS), args: []interface{}{true, "27.56", "10"}, expectErr: true},
+	{condition: Within(PostGIS), args: []interface{}{"54", "27.4", "53.8", "27.7"}, expectErr: true},
+	{condition: Within(PostGIS), args: []interface{}{"53.8", "27.4", "54"}, expectErr: true},
+}
+
+func TestConditions(t *testing.T) {
+	for i, tt := range conditionTests {
+		meta := fmt.Sprintf("test %d", i)
+		s, err := tt.condition("location", tt.args...)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: unexpected error status: got %v, want %v", meta, err, tt.expectErr)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("%s: expected ErrInvalidArgument, got %s", meta, err)
+			}
+			continue
+		}
+		sql, args, err := s.ToSQL()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", meta, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("%s: expected sql %q, got %q", meta, tt.sql, sql)
+		}
+		if !reflect.DeepEqual(args, tt.sqlArgs) {
+			t.Errorf("%s: expected args %+v, got %+v", meta, tt.sqlArgs, args)
+		}
+	}
+}
+
+func TestDistanceSort(t *testing.T) {
+	builder := q2sql.NewResourceSelectBuilder(
+		"stores",
+		q2sql.MapTranslator(map[string]string{"location": "location", "name": "name"}),
+		q2sql.WithDefaultFields([]string{"id", "name"}),
+		q2sql.AllowFiltering(
+			q2sql.AllowedConditions{"location": []string{NameNear, NameWithin}},
+			Conditions(PostGIS),
+			q2sql.DefaultFilterExpressionParser,
+		),
+		q2sql.AllowSortingByFields([]string{"name"}),
+		q2sql.AllowSortingByExpressions(map[string]q2sql.SortExpression{
+			"distance": DistanceSort(PostGIS, "location", "origin"),
+		}),
+	)
+	query, err := qparser.ParseQuery("filter[location]=near:53.9,27.56,5000&sort=distance,-name&origin=53.9,27.56")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb, err := builder.Build(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err := sb.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "SELECT id, name FROM stores " +
+		"WHERE ST_DWithin(location::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?) " +
+		"ORDER BY ST_Distance(location::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography) ASC, name DESC"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q\n\tgot %q", expectedSQL, sql)
+	}
+	expectedArgs := []interface{}{27.56, 53.9, 5000.0, 27.56, 53.9}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %+v, got %+v", expectedArgs, args)
+	}
+
+	for _, qs := range []string{"sort=distance", "sort=distance&origin=100,0"} {
+		query, err = qparser.ParseQuery(qs)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if _, err = builder.Build(context.Background(), query); err == nil {
+			t.Errorf("%q: expected error", qs)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package q2sql
+
+import "fmt"
+
+// These constants are names of the limits reported by the LimitError
+const (
+	LimitFilters           = "filters"
+	LimitConditionArgs     = "condition arguments"
+	LimitSortFields        = "sort fields"
+	LimitSelectFields      = "select fields"
+	LimitBoundParams       = "bound parameters"
+	LimitLikePatternLength = "like pattern length"
+)
+
+// Limits restricts complexity of the query in order to protect against abuse.
+// Zero value of a limit means that it is not restricted
+type Limits struct {
+	// MaxFilters is the maximum number of the filters
+	MaxFilters int `json:"maxFilters,omitempty"`
+	// MaxConditionArgs is the maximum number of arguments of a single filter, e.g. "in" values
+	MaxConditionArgs int `json:"maxConditionArgs,omitempty"`
+	// MaxSortFields is the maximum number of the sort fields
+	MaxSortFields int `json:"maxSortFields,omitempty"`
+	// MaxSelectFields is the maximum number of the requested fields
+	MaxSelectFields int `json:"maxSelectFields,omitempty"`
+	// MaxBoundParams is the maximum total number of the arguments bound to the SQL statement
+	MaxBoundParams int `json:"maxBoundParams,omitempty"`
+	// MaxLikePatternLength is the maximum length of the LIKE condition pattern
+	MaxLikePatternLength int `json:"maxLikePatternLength,omitempty"`
+}
+
+// LimitError is returned when the query exceeds one of the Limits
+type LimitError struct {
+	Limit  string
+	Field  string
+	Max    int
+	Actual int
+}
+
+func (l *LimitError) Error() string {
+	if l.Field != "" {
+		return fmt.Sprintf("filter of the field %q exceeds the limit of %s: %d > %d", l.Field, l.Limit, l.Actual, l.Max)
+	}
+	return fmt.Sprintf("query exceeds the limit of %s: %d > %d", l.Limit, l.Actual, l.Max)
+}
+
+func checkLimit(limit string, maxValue, actual int, field string) error {
+	if maxValue > 0 && actual > maxValue {
+		return &LimitError{Limit: limit, Field: field, Max: maxValue, Actual: actual}
+	}
+	return nil
+}
+
+// checkLikePattern checks length of the pattern if the condition is the Like expression
+func (l *Limits) checkLikePattern(cond Sqlizer, field string) error {
+	if l == nil || l.MaxLikePatternLength <= 0 {
+		return nil
+	}
+	like, ok := cond.(*Like)
+	if !ok {
+		return nil
+	}
+	var length int
+	switch v := like.Value.(type) {
+	case string:
+		length = len(v)
+	case []byte:
+		length = len(v)
+	default:
+		return nil
+	}
+	if length > l.MaxLikePatternLength {
+		return &LimitError{Limit: LimitLikePatternLength, Field: field, Max: l.MaxLikePatternLength, Actual: length}
+	}
+	return nil
+}
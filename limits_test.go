@@ -0,0 +1,82 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type limitsTest struct {
+	query string
+	limit string
+}
+
+var limitsTests = []limitsTest{
+	{query: "filter[id]=in:1,2,3&filter[title]=like:abc"},
+	{query: "filter[id]=in:1&filter[title]=like:a&filter[body]=like:b", limit: LimitFilters},
+	{query: "filter[id]=in:1,2,3,4", limit: LimitConditionArgs},
+	{query: "sort=id,title,body", limit: LimitSortFields},
+	{query: "fields[articles]=id,title,body", limit: LimitSelectFields},
+	{query: "filter[title]=like:" + fmt.Sprintf("%011d", 0), limit: LimitLikePatternLength},
+	{query: "filter[id]=in:1,2,3&filter[title]=like:abc", limit: LimitBoundParams},
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{
+		MaxFilters:           2,
+		MaxConditionArgs:     3,
+		MaxSortFields:        2,
+		MaxSelectFields:      2,
+		MaxLikePatternLength: 10,
+	}
+	translator := MapTranslator(map[string]string{"id": "id", "title": "title", "body": "body"})
+	conditions := ConditionMap{
+		"in": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &In{Field: field, Values: args}, nil
+		},
+		"like": func(field string, args ...interface{}) (Sqlizer, error) {
+			return &Like{Field: field, Value: args[0]}, nil
+		},
+	}
+	for i, tt := range limitsTests {
+		meta := fmt.Sprintf("test %d (%q)", i, tt.query)
+		l := limits
+		if tt.limit == LimitBoundParams {
+			l.MaxBoundParams = 3
+		}
+		builder := NewResourceSelectBuilder(
+			resourceName,
+			translator,
+			WithDefaultFields([]string{"id", "title", "body"}),
+			AllowFiltering(
+				AllowedConditions{"id": []string{"in"}, "title": []string{"like"}, "body": []string{"like"}},
+				conditions,
+				DefaultFilterExpressionParser,
+			),
+			AllowSortingByFields([]string{"id", "title", "body"}),
+			WithLimits(l),
+		)
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", meta, err)
+		}
+		_, err = builder.Build(context.Background(), query)
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", meta, err)
+			}
+			continue
+		}
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%s: expected *LimitError, got %v", meta, err)
+			continue
+		}
+		if limitErr.Limit != tt.limit {
+			t.Errorf("%s: expected limit %q, got %q", meta, tt.limit, limitErr.Limit)
+		}
+	}
+}
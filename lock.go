@@ -0,0 +1,87 @@
+package q2sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LockStrength is the strength of the row lock
+type LockStrength int
+
+const (
+	ForUpdate LockStrength = iota + 1
+	ForNoKeyUpdate
+	ForShare
+	ForKeyShare
+)
+
+func (s LockStrength) String() string {
+	switch s {
+	case ForUpdate:
+		return "FOR UPDATE"
+	case ForNoKeyUpdate:
+		return "FOR NO KEY UPDATE"
+	case ForShare:
+		return "FOR SHARE"
+	case ForKeyShare:
+		return "FOR KEY SHARE"
+	}
+	return ""
+}
+
+// LockWait specifies what happens if the rows are already locked
+type LockWait int
+
+const (
+	// LockWaitDefault waits until the rows are unlocked
+	LockWaitDefault LockWait = iota
+	// NoWait reports an error instead of waiting
+	NoWait
+	// SkipLocked skips the locked rows
+	SkipLocked
+)
+
+func (w LockWait) String() string {
+	switch w {
+	case NoWait:
+		return "NOWAIT"
+	case SkipLocked:
+		return "SKIP LOCKED"
+	}
+	return ""
+}
+
+// Lock is the row locking clause, e.g. "FOR UPDATE OF jobs SKIP LOCKED"
+type Lock struct {
+	Strength LockStrength
+	// Of restricts locking to the listed tables
+	Of   []string
+	Wait LockWait
+}
+
+// toSQL renders the clause, an error is returned if the dialect does not support it
+func (l *Lock) toSQL(dialect Dialect) (string, error) {
+	strength := l.Strength.String()
+	if strength == "" {
+		return "", Error("lock strength is not specified")
+	}
+	switch dialect {
+	case DialectSQLite:
+		return "", fmt.Errorf("%w: %s is not supported by %s", ErrUnsupportedByDialect, strength, dialect)
+	case DialectMySQL:
+		if l.Strength == ForNoKeyUpdate || l.Strength == ForKeyShare {
+			return "", fmt.Errorf("%w: %s is not supported by %s", ErrUnsupportedByDialect, strength, dialect)
+		}
+	}
+	sql := &strings.Builder{}
+	sql.WriteString(strength)
+	if len(l.Of) > 0 {
+		sql.WriteString(" OF ")
+		sql.WriteString(strings.Join(l.Of, ", "))
+	}
+	if wait := l.Wait.String(); wait != "" {
+		sql.WriteString(" ")
+		sql.WriteString(wait)
+	}
+	return sql.String(), nil
+}
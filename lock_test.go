@@ -0,0 +1,91 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type lockTest struct {
+	dialect     Dialect
+	lock        Lock
+	sql         string
+	unsupported bool
+	expectErr   bool
+}
+
+var lockTests = []lockTest{
+	{lock: Lock{Strength: ForUpdate}, sql: "SELECT id FROM jobs FOR UPDATE"},
+	{lock: Lock{Strength: ForKeyShare}, sql: "SELECT id FROM jobs FOR KEY SHARE"},
+	{
+		dialect: DialectPostgres,
+		lock:    Lock{Strength: ForUpdate, Of: []string{"jobs"}, Wait: SkipLocked},
+		sql:     "SELECT id FROM jobs FOR UPDATE OF jobs SKIP LOCKED",
+	},
+	{
+		dialect: DialectPostgres,
+		lock:    Lock{Strength: ForNoKeyUpdate, Wait: NoWait},
+		sql:     "SELECT id FROM jobs FOR NO KEY UPDATE NOWAIT",
+	},
+	{
+		dialect: DialectMySQL,
+		lock:    Lock{Strength: ForShare, Of: []string{"jobs", "queues"}, Wait: NoWait},
+		sql:     "SELECT id FROM jobs FOR SHARE OF jobs, queues NOWAIT",
+	},
+	{dialect: DialectMySQL, lock: Lock{Strength: ForKeyShare}, unsupported: true},
+	{dialect: DialectMySQL, lock: Lock{Strength: ForNoKeyUpdate}, unsupported: true},
+	{dialect: DialectSQLite, lock: Lock{Strength: ForUpdate}, unsupported: true},
+	{lock: Lock{Wait: SkipLocked}, expectErr: true},
+}
+
+func TestSelectBuilderLock(t *testing.T) {
+	for _, tt := range lockTests {
+		b := new(SelectBuilder).Select([]string{"id"}).From("jobs").Lock(tt.lock)
+		b.Dialect = tt.dialect
+		sql, _, err := b.ToSQL()
+		if errors.Is(err, ErrUnsupportedByDialect) != tt.unsupported {
+			t.Errorf("%+v: unexpected unsupported error status: got %v", tt.lock, err)
+			continue
+		}
+		if (err != nil) != (tt.expectErr || tt.unsupported) {
+			t.Errorf("%+v: unexpected error status: got %v", tt.lock, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("%+v: expected sql %q, got %q", tt.lock, tt.sql, sql)
+		}
+	}
+}
+
+func TestResourceSelectBuilderLock(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"jobs",
+		MapTranslator(map[string]string{"id": "id", "createdAt": "created_at"}),
+		WithDefaultFields([]string{"id"}),
+		AllowSortingByFields([]string{"created_at"}),
+		WithDialect(DialectSQLite),
+	)
+	query, err := qparser.ParseQuery("sort=createdAt")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb, err := builder.Build(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb.Limit(10).Lock(Lock{Strength: ForUpdate, Wait: SkipLocked})
+	if _, _, err = sb.ToSQL(); !errors.Is(err, ErrUnsupportedByDialect) {
+		t.Errorf("expected ErrUnsupportedByDialect, got %v", err)
+	}
+	sb.Dialect = DialectPostgres
+	sql, _, err := sb.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "SELECT id FROM jobs ORDER BY created_at ASC LIMIT 10 FOR UPDATE SKIP LOCKED"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q, got %q", expectedSQL, sql)
+	}
+}
@@ -0,0 +1,322 @@
+package q2sql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/velmie/qparser"
+)
+
+const (
+	// ErrUnfiltered is returned when UPDATE or DELETE has no conditions and it is not explicitly allowed
+	ErrUnfiltered = Error("statement without conditions affects all rows, it must be explicitly allowed")
+	// ErrMutationParameter is returned when the query of UPDATE or DELETE contains the parameters
+	// which cannot be applied to these statements
+	ErrMutationParameter = Error("pagination, sorting and aggregation cannot be applied to UPDATE or DELETE")
+	// ErrMutationExtension is returned when an extension changes the parts of the query
+	// other than WHERE while building UPDATE or DELETE conditions
+	ErrMutationExtension = Error("extension changes the clause which cannot be applied to UPDATE or DELETE")
+)
+
+// BuildConditions validates the query filters against the builder rules (and the policy if any)
+// and returns the conditions, so they could be used in UPDATE or DELETE statements.
+// The filters are the conditions requested by the client, they are empty if the query has no filters.
+// The scope are the conditions added by the extensions (e.g. extension.Scope), they are applied
+// in addition to the filters and do not make the statement filtered.
+// ErrMutationParameter is returned if the query contains "page", "sort" or the aggregation parameters.
+// ErrMutationExtension is returned if an extension adds columns, FROM, joins, grouping, HAVING,
+// common table expressions or the lock. The limit, offset and order set by the extensions are the defaults
+// of the listing (the query cannot request them), they are not applied
+func (s *ResourceSelectBuilder) BuildConditions(
+	ctx context.Context,
+	query *qparser.Query,
+) (filters, scope []Sqlizer, err error) {
+	if err = checkMutationQuery(query); err != nil {
+		return nil, nil, err
+	}
+	permissions, err := s.resolvePermissions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.checkQueryLimits(query); err != nil {
+		TraceFromContext(ctx).record(TraceStep{Stage: TraceStageLimits}, nil, err)
+		return nil, nil, err
+	}
+	filters, err = s.retrieveFilterConditions(ctx, query, permissions, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := new(SelectBuilder)
+	for _, extension := range s.extensions {
+		if err = s.runExtension(ctx, extension, query, b); err != nil {
+			return nil, nil, err
+		}
+	}
+	if clause := selectOnlyClause(b); clause != "" {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMutationExtension, clause)
+	}
+	return filters, b.WhereParts, nil
+}
+
+// selectOnlyClause returns the name of the first clause set in the builder which UPDATE and DELETE
+// cannot have, it returns an empty string if there are none
+func selectOnlyClause(b *SelectBuilder) string {
+	switch {
+	case len(b.CTEs) > 0:
+		return "WITH"
+	case b.IsDistinct || len(b.Columns) > 0:
+		return "columns"
+	case b.FromPart != nil:
+		return "FROM"
+	case len(b.Joins) > 0:
+		return "JOIN"
+	case len(b.GroupBys) > 0:
+		return "GROUP BY"
+	case len(b.HavingParts) > 0:
+		return "HAVING"
+	case b.LockPart != nil:
+		return "lock"
+	}
+	return ""
+}
+
+// BuildDelete creates DeleteBuilder of the resource with the conditions built from the query
+func (s *ResourceSelectBuilder) BuildDelete(ctx context.Context, query *qparser.Query) (*DeleteBuilder, error) {
+	filters, scope, err := s.BuildConditions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	d := Delete(s.resourceName).Where(filters...).Scope(scope...)
+	d.Dialect = s.dialect
+	return d, nil
+}
+
+// BuildUpdate creates UpdateBuilder of the resource with the conditions built from the query
+func (s *ResourceSelectBuilder) BuildUpdate(ctx context.Context, query *qparser.Query) (*UpdateBuilder, error) {
+	filters, scope, err := s.BuildConditions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	u := Update(s.resourceName).Where(filters...).Scope(scope...)
+	u.Dialect = s.dialect
+	return u, nil
+}
+
+// checkMutationQuery rejects the parameters which have no meaning for UPDATE and DELETE
+func checkMutationQuery(query *qparser.Query) error {
+	switch {
+	case query.Page != nil:
+		return fmt.Errorf("%w: %q", ErrMutationParameter, pageParameter)
+	case len(query.Sort) > 0:
+		return fmt.Errorf("%w: %q", ErrMutationParameter, sortParameter)
+	case query.Values.Get(GroupParameter) != "":
+		return fmt.Errorf("%w: %q", ErrMutationParameter, GroupParameter)
+	case query.Values.Get(AggregateParameter) != "":
+		return fmt.Errorf("%w: %q", ErrMutationParameter, AggregateParameter)
+	case len(query.Values[HavingParameter]) > 0:
+		return fmt.Errorf("%w: %q", ErrMutationParameter, HavingParameter)
+	}
+	return nil
+}
+
+// DeleteBuilder builds "DELETE FROM table WHERE ... RETURNING ..." statement
+type DeleteBuilder struct {
+	Table      string
+	WhereParts []Sqlizer
+	// ScopeParts are the mandatory conditions which are always applied,
+	// they do not count as the conditions for AllowUnfiltered
+	ScopeParts       []Sqlizer
+	ReturningColumns []string
+	// AllowUnfiltered allows the statement without conditions which deletes all rows
+	AllowUnfiltered bool
+	Dialect         Dialect
+}
+
+// Delete creates DeleteBuilder for the table
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{Table: table}
+}
+
+func (d *DeleteBuilder) Where(conditions ...Sqlizer) *DeleteBuilder {
+	d.WhereParts = append(d.WhereParts, conditions...)
+	return d
+}
+
+// Scope adds the mandatory conditions, see DeleteBuilder.ScopeParts
+func (d *DeleteBuilder) Scope(conditions ...Sqlizer) *DeleteBuilder {
+	d.ScopeParts = append(d.ScopeParts, conditions...)
+	return d
+}
+
+// Returning adds "RETURNING" clause, it is not supported by MySQL
+func (d *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	d.ReturningColumns = append(d.ReturningColumns, columns...)
+	return d
+}
+
+// Unfiltered allows deleting all rows if there are no conditions
+func (d *DeleteBuilder) Unfiltered() *DeleteBuilder {
+	d.AllowUnfiltered = true
+	return d
+}
+
+func (d *DeleteBuilder) ToSQL() (string, []interface{}, error) {
+	if d.Table == "" {
+		return "", nil, Error("delete statement must have a table")
+	}
+	sql := getBuffer()
+	defer putBuffer(sql)
+	sql.WriteString("DELETE FROM ")
+	sql.WriteString(d.Table)
+	args, err := writeMutationSuffix(sql, make([]interface{}, 0), d.WhereParts, d.ScopeParts, d.AllowUnfiltered, d.ReturningColumns, d.Dialect)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql.String(), args, nil
+}
+
+// SetClause is "column = value" item of the SET clause
+type SetClause struct {
+	Column string
+	Value  interface{}
+}
+
+// UpdateBuilder builds "UPDATE table SET ... WHERE ... RETURNING ..." statement
+type UpdateBuilder struct {
+	Table      string
+	SetClauses []SetClause
+	WhereParts []Sqlizer
+	// ScopeParts are the mandatory conditions which are always applied,
+	// they do not count as the conditions for AllowUnfiltered
+	ScopeParts       []Sqlizer
+	ReturningColumns []string
+	// AllowUnfiltered allows the statement without conditions which updates all rows
+	AllowUnfiltered bool
+	Dialect         Dialect
+}
+
+// Update creates UpdateBuilder for the table
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{Table: table}
+}
+
+// Set adds "column = value" to the SET clause, if the value is Sqlizer it is rendered as is,
+// e.g. Set("views", RawSQL("views + 1"))
+func (u *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	u.SetClauses = append(u.SetClauses, SetClause{Column: column, Value: value})
+	return u
+}
+
+func (u *UpdateBuilder) Where(conditions ...Sqlizer) *UpdateBuilder {
+	u.WhereParts = append(u.WhereParts, conditions...)
+	return u
+}
+
+// Scope adds the mandatory conditions, see UpdateBuilder.ScopeParts
+func (u *UpdateBuilder) Scope(conditions ...Sqlizer) *UpdateBuilder {
+	u.ScopeParts = append(u.ScopeParts, conditions...)
+	return u
+}
+
+// Returning adds "RETURNING" clause, it is not supported by MySQL
+func (u *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	u.ReturningColumns = append(u.ReturningColumns, columns...)
+	return u
+}
+
+// Unfiltered allows updating all rows if there are no conditions
+func (u *UpdateBuilder) Unfiltered() *UpdateBuilder {
+	u.AllowUnfiltered = true
+	return u
+}
+
+func (u *UpdateBuilder) ToSQL() (string, []interface{}, error) {
+	if u.Table == "" {
+		return "", nil, Error("update statement must have a table")
+	}
+	if len(u.SetClauses) == 0 {
+		return "", nil, Error("update statement must have at least one SET clause")
+	}
+	sql := getBuffer()
+	defer putBuffer(sql)
+	args := make([]interface{}, 0)
+	sql.WriteString("UPDATE ")
+	sql.WriteString(u.Table)
+	sql.WriteString(" SET ")
+	for i, set := range u.SetClauses {
+		if i > 0 {
+			sql.WriteString(", ")
+		}
+		sql.WriteString(set.Column)
+		sql.WriteString(" = ")
+		if value, ok := set.Value.(Sqlizer); ok {
+			var err error
+			args, err = WriteSQL(sql, args, value)
+			if err != nil {
+				return "", nil, err
+			}
+			continue
+		}
+		sql.WriteString("?")
+		args = append(args, set.Value)
+	}
+	args, err := writeMutationSuffix(sql, args, u.WhereParts, u.ScopeParts, u.AllowUnfiltered, u.ReturningColumns, u.Dialect)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql.String(), args, nil
+}
+
+// writeMutationSuffix writes "WHERE" and "RETURNING" clauses of UPDATE and DELETE statements.
+// Only the where conditions which render non-empty SQL make the statement filtered, the scope conditions
+// are placed first and the where conditions are grouped in parentheses, so they cannot bypass the scope
+func writeMutationSuffix(
+	sql *bytes.Buffer,
+	args []interface{},
+	where []Sqlizer,
+	scope []Sqlizer,
+	allowUnfiltered bool,
+	returning []string,
+	dialect Dialect,
+) ([]interface{}, error) {
+	whereSQL := getBuffer()
+	defer putBuffer(whereSQL)
+	whereArgs, err := appendToSQL(where, whereSQL, " AND ", nil)
+	if err != nil {
+		return nil, err
+	}
+	if whereSQL.Len() == 0 && !allowUnfiltered {
+		return nil, ErrUnfiltered
+	}
+	scopeSQL := getBuffer()
+	defer putBuffer(scopeSQL)
+	args, err = appendToSQL(scope, scopeSQL, " AND ", args)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case scopeSQL.Len() > 0 && whereSQL.Len() > 0:
+		sql.WriteString(" WHERE ")
+		sql.Write(scopeSQL.Bytes())
+		sql.WriteString(" AND (")
+		sql.Write(whereSQL.Bytes())
+		sql.WriteString(")")
+	case scopeSQL.Len() > 0:
+		sql.WriteString(" WHERE ")
+		sql.Write(scopeSQL.Bytes())
+	case whereSQL.Len() > 0:
+		sql.WriteString(" WHERE ")
+		sql.Write(whereSQL.Bytes())
+	}
+	args = append(args, whereArgs...)
+	if len(returning) > 0 {
+		if dialect == DialectMySQL {
+			return nil, fmt.Errorf("%w: RETURNING is not supported by %s", ErrUnsupportedByDialect, dialect)
+		}
+		sql.WriteString(" RETURNING ")
+		sql.WriteString(strings.Join(returning, ", "))
+	}
+	return args, nil
+}
@@ -0,0 +1,328 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type mutationTest struct {
+	name        string
+	b           Sqlizer
+	sql         string
+	args        []interface{}
+	unfiltered  bool
+	unsupported bool
+	expectErr   bool
+}
+
+var mutationTests = []mutationTest{
+	{
+		name: "delete with conditions",
+		b:    Delete("articles").Where(&Eq{Field: "status", Value: "spam"}, &Lt{Field: "rating", Value: 0}),
+		sql:  "DELETE FROM articles WHERE status = ? AND rating < ?",
+		args: []interface{}{"spam", 0},
+	},
+	{
+		name:       "delete without conditions",
+		b:          Delete("articles"),
+		unfiltered: true,
+	},
+	{
+		name: "delete all explicitly",
+		b:    Delete("articles").Unfiltered(),
+		sql:  "DELETE FROM articles",
+		args: []interface{}{},
+	},
+	{
+		name: "delete returning",
+		b:    Delete("articles").Where(&Eq{Field: "id", Value: 1}).Returning("id", "title"),
+		sql:  "DELETE FROM articles WHERE id = ? RETURNING id, title",
+		args: []interface{}{1},
+	},
+	{
+		name:       "delete with scope only",
+		b:          Delete("articles").Scope(&Eq{Field: "tenant_id", Value: 1}),
+		unfiltered: true,
+	},
+	{
+		name: "delete with scope",
+		b:    Delete("articles").Scope(&Eq{Field: "tenant_id", Value: 1}).Where(RawSQL("a = 1 OR b = 2")),
+		sql:  "DELETE FROM articles WHERE tenant_id = ? AND (a = 1 OR b = 2)",
+		args: []interface{}{1},
+	},
+	{
+		name:       "delete with conditions rendering empty SQL",
+		b:          Delete("articles").Where(RawSQL(""), &RawSQLWithArgs{}),
+		unfiltered: true,
+	},
+	{
+		name:       "delete with scope and conditions rendering empty SQL",
+		b:          Delete("articles").Scope(&Eq{Field: "tenant_id", Value: 1}).Where(RawSQL("")),
+		unfiltered: true,
+	},
+	{
+		name: "delete all explicitly with conditions rendering empty SQL",
+		b:    Delete("articles").Scope(&Eq{Field: "tenant_id", Value: 1}).Where(RawSQL("")).Unfiltered(),
+		sql:  "DELETE FROM articles WHERE tenant_id = ?",
+		args: []interface{}{1},
+	},
+	{
+		name:        "delete returning in mysql",
+		b:           &DeleteBuilder{Table: "articles", WhereParts: []Sqlizer{RawSQL("id = 1")}, ReturningColumns: []string{"id"}, Dialect: DialectMySQL},
+		unsupported: true,
+	},
+	{
+		name: "update",
+		b: Update("articles").
+			Set("status", "hidden").
+			Set("views", RawSQL("views + 1")).
+			Where(&Eq{Field: "author", Value: "x"}).
+			Returning("id"),
+		sql:  "UPDATE articles SET status = ?, views = views + 1 WHERE author = ? RETURNING id",
+		args: []interface{}{"hidden", "x"},
+	},
+	{
+		name:       "update without conditions",
+		b:          Update("articles").Set("status", "hidden"),
+		unfiltered: true,
+	},
+	{
+		name:      "update without set",
+		b:         Update("articles").Where(RawSQL("id = 1")),
+		expectErr: true,
+	},
+	{
+		name:      "delete without table",
+		b:         Delete("").Unfiltered(),
+		expectErr: true,
+	},
+}
+
+func TestMutationBuilders(t *testing.T) {
+	for _, tt := range mutationTests {
+		sql, args, err := tt.b.ToSQL()
+		if errors.Is(err, ErrUnfiltered) != tt.unfiltered {
+			t.Errorf("%s: unexpected unfiltered error status: got %v", tt.name, err)
+			continue
+		}
+		if errors.Is(err, ErrUnsupportedByDialect) != tt.unsupported {
+			t.Errorf("%s: unexpected unsupported error status: got %v", tt.name, err)
+			continue
+		}
+		if (err != nil) != (tt.expectErr || tt.unfiltered || tt.unsupported) {
+			t.Errorf("%s: unexpected error status: got %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("%s: expected sql %q, got %q", tt.name, tt.sql, sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %+v, got %+v", tt.name, tt.args, args)
+		}
+	}
+}
+
+func TestResourceSelectBuilderMutations(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"articles",
+		MapTranslator(map[string]string{"id": "id", "status": "status"}),
+		WithDefaultFields([]string{"id"}),
+		AllowFiltering(
+			AllowedConditions{"status": []string{filterEq}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithDialect(DialectPostgres),
+	)
+	ctx := context.Background()
+
+	query, err := qparser.ParseQuery("filter[status]=eq:spam")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	d, err := builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err := d.Returning("id").ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if sql != "DELETE FROM articles WHERE status = ? RETURNING id" || !reflect.DeepEqual(args, []interface{}{"spam"}) {
+		t.Errorf("unexpected delete statement %q %+v", sql, args)
+	}
+
+	u, err := builder.BuildUpdate(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err = u.Set("status", "deleted").ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if sql != "UPDATE articles SET status = ? WHERE status = ?" || !reflect.DeepEqual(args, []interface{}{"deleted", "spam"}) {
+		t.Errorf("unexpected update statement %q %+v", sql, args)
+	}
+
+	query, err = qparser.ParseQuery("")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	d, err = builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, _, err = d.ToSQL(); !errors.Is(err, ErrUnfiltered) {
+		t.Errorf("expected ErrUnfiltered, got %v", err)
+	}
+
+	query, err = qparser.ParseQuery("filter[id]=eq:1")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	var filterErr *FilterError
+	if _, _, err = builder.BuildConditions(ctx, query); !errors.As(err, &filterErr) {
+		t.Errorf("expected *FilterError, got %v", err)
+	}
+
+	query, err = qparser.ParseQuery("filter[status]=eq:spam&page[limit]=5")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = builder.BuildDelete(ctx, query); !errors.Is(err, ErrMutationParameter) {
+		t.Errorf("expected ErrMutationParameter, got %v", err)
+	}
+
+	query, err = qparser.ParseQuery("filter[status]=eq:spam&sort=-id")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = builder.BuildUpdate(ctx, query); !errors.Is(err, ErrMutationParameter) {
+		t.Errorf("expected ErrMutationParameter, got %v", err)
+	}
+}
+
+func TestResourceSelectBuilderMutationsScope(t *testing.T) {
+	tenant := func(_ context.Context, _ *qparser.Query, b *SelectBuilder) error {
+		b.Where(&Eq{Field: "tenant_id", Value: 7})
+		return nil
+	}
+	builder := NewResourceSelectBuilder(
+		"articles",
+		MapTranslator(map[string]string{"id": "id", "status": "status"}),
+		WithDefaultFields([]string{"id"}),
+		AllowFiltering(
+			AllowedConditions{"status": []string{filterEq}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		Extend(tenant),
+	)
+	ctx := context.Background()
+
+	query, err := qparser.ParseQuery("")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	filters, scope, err := builder.BuildConditions(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(filters) != 0 || len(scope) != 1 {
+		t.Errorf("expected no filters and one scope condition, got %+v %+v", filters, scope)
+	}
+	d, err := builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, _, err = d.ToSQL(); !errors.Is(err, ErrUnfiltered) {
+		t.Errorf("expected ErrUnfiltered, got %v", err)
+	}
+	sql, args, err := d.Unfiltered().ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if sql != "DELETE FROM articles WHERE tenant_id = ?" || !reflect.DeepEqual(args, []interface{}{7}) {
+		t.Errorf("unexpected delete statement %q %+v", sql, args)
+	}
+
+	query, err = qparser.ParseQuery("filter[status]=eq:spam")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	u, err := builder.BuildUpdate(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err = u.Set("status", "deleted").ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if sql != "UPDATE articles SET status = ? WHERE tenant_id = ? AND (status = ?)" ||
+		!reflect.DeepEqual(args, []interface{}{"deleted", 7, "spam"}) {
+		t.Errorf("unexpected update statement %q %+v", sql, args)
+	}
+}
+
+func TestResourceSelectBuilderMutationsExtensions(t *testing.T) {
+	join := func(_ context.Context, _ *qparser.Query, b *SelectBuilder) error {
+		b.Join(RawSQL("JOIN authors ON authors.id = articles.author_id"))
+		return nil
+	}
+	defaultLimit := func(_ context.Context, _ *qparser.Query, b *SelectBuilder) error {
+		b.Limit(10)
+		return nil
+	}
+	conditions := testConditions()
+	conditions["empty"] = func(string, ...interface{}) (Sqlizer, error) {
+		return RawSQL(""), nil
+	}
+	filtering := AllowFiltering(
+		AllowedConditions{"status": []string{filterEq, "empty"}},
+		conditions,
+		DefaultFilterExpressionParser,
+	)
+	translator := MapTranslator(map[string]string{"id": "id", "status": "status"})
+	ctx := context.Background()
+
+	query, err := qparser.ParseQuery("filter[status]=eq:spam")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	builder := NewResourceSelectBuilder("articles", translator, filtering, Extend(join))
+	if _, err = builder.BuildDelete(ctx, query); !errors.Is(err, ErrMutationExtension) {
+		t.Errorf("expected ErrMutationExtension, got %v", err)
+	}
+
+	builder = NewResourceSelectBuilder("articles", translator, filtering, Extend(defaultLimit))
+	d, err := builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err := d.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if sql != "DELETE FROM articles WHERE status = ?" || !reflect.DeepEqual(args, []interface{}{"spam"}) {
+		t.Errorf("unexpected delete statement %q %+v", sql, args)
+	}
+
+	query, err = qparser.ParseQuery("filter[status]=empty:spam")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	d, err = builder.BuildDelete(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, _, err = d.ToSQL(); !errors.Is(err, ErrUnfiltered) {
+		t.Errorf("expected ErrUnfiltered, got %v", err)
+	}
+}
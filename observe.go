@@ -0,0 +1,168 @@
+package q2sql
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/velmie/qparser"
+)
+
+// These are names of the spans started by the package
+const (
+	SpanBuild     = "q2sql.Build"
+	SpanExtension = "q2sql.Extension"
+	SpanSelect    = "q2sql.Select"
+)
+
+// These are keys of the span attributes and the log record fields
+const (
+	AttributeResource  = "q2sql.resource"
+	AttributeFilters   = "q2sql.filters"
+	AttributeFields    = "q2sql.fields"
+	AttributeExtension = "q2sql.extension"
+	AttributeRows      = "q2sql.rows"
+	AttributeStatement = "db.statement"
+)
+
+// Attribute is a key-value pair attached to the span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a unit of work reported to the Tracer.
+// It allows adapting any tracing library (e.g. OpenTelemetry) without depending on it
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, the returned context carries the span so nested spans could refer to it
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Logger receives debug records, it is satisfied by *slog.Logger
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...any)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
+
+// observe reports the attributes and the error to the span and the logger
+func observe(ctx context.Context, span Span, logger Logger, msg string, err error, attributes []Attribute) {
+	span.SetAttributes(attributes...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	if logger == nil {
+		return
+	}
+	args := make([]any, 0, 2*len(attributes)+2) //nolint:gomnd // key and value of each attribute
+	for _, attribute := range attributes {
+		args = append(args, attribute.Key, attribute.Value)
+	}
+	if err != nil {
+		args = append(args, "error", err.Error())
+	}
+	logger.DebugContext(ctx, msg, args...)
+}
+
+// buildAttributes describes the built query, the SQL template is added without arguments
+func (s *ResourceSelectBuilder) buildAttributes(query *qparser.Query, b *SelectBuilder, rendered *renderedQuery) []Attribute {
+	attributes := []Attribute{
+		{Key: AttributeResource, Value: s.resourceName},
+		{Key: AttributeFilters, Value: len(query.Filters)},
+	}
+	if b == nil {
+		return attributes
+	}
+	fields := make([]string, 0, len(b.Columns))
+	for _, column := range b.Columns {
+		if sql, _, err := column.ToSQL(); err == nil {
+			fields = append(fields, sql)
+		}
+	}
+	attributes = append(attributes, Attribute{Key: AttributeFields, Value: strings.Join(fields, ", ")})
+	if sql, _, err := rendered.render(b); err == nil {
+		attributes = append(attributes, Attribute{Key: AttributeStatement, Value: sql})
+	}
+	return attributes
+}
+
+// MemorySpan is a span recorded by the MemoryTracer
+type MemorySpan struct {
+	Name       string
+	Parent     *MemorySpan
+	Attributes []Attribute
+	Errors     []error
+	Ended      bool
+	tracer     *MemoryTracer
+}
+
+func (s *MemorySpan) SetAttributes(attributes ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Attributes = append(s.Attributes, attributes...)
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+}
+
+func (s *MemorySpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Ended = true
+}
+
+// Attribute returns the value of the attribute and whether it is set
+func (s *MemorySpan) Attribute(key string) (interface{}, bool) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+type memorySpanKey struct{}
+
+// MemoryTracer records spans in memory, it is intended for tests
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+func (t *MemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &MemorySpan{Name: name, tracer: t}
+	span.Parent, _ = ctx.Value(memorySpanKey{}).(*MemorySpan)
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, memorySpanKey{}, span), span
+}
+
+// Spans returns the started spans in the order they were started
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan(nil), t.spans...)
+}
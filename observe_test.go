@@ -0,0 +1,177 @@
+package q2sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type logRecord struct {
+	msg  string
+	args []any
+}
+
+type memoryLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *memoryLogger) DebugContext(_ context.Context, msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{msg: msg, args: args})
+}
+
+func (r logRecord) value(key string) (any, bool) {
+	for i := 0; i+1 < len(r.args); i += 2 {
+		if r.args[i] == key {
+			return r.args[i+1], true
+		}
+	}
+	return nil, false
+}
+
+var errObserveExtension = errors.New("extension failed")
+
+func failingExtension(ctx context.Context, query *qparser.Query, builder *SelectBuilder) error {
+	if query.Values.Get("fail") != "" {
+		return errObserveExtension
+	}
+	return nil
+}
+
+func newObservedBuilder(tracer Tracer, logger Logger, options ...ResourceSelectBuilderOption) *ResourceSelectBuilder {
+	return newArticlesBuilder(append([]ResourceSelectBuilderOption{
+		Extend(failingExtension),
+		WithTracer(tracer),
+		WithLogger(logger),
+	}, options...)...)
+}
+
+func TestBuildTracing(t *testing.T) {
+	tracer := &MemoryTracer{}
+	logger := &memoryLogger{}
+	query, err := qparser.ParseQuery("filter[title]=eq:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = newObservedBuilder(tracer, logger).Build(context.Background(), query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	spans := tracer.Spans()
+	if len(spans) != 2 || spans[0].Name != SpanBuild || spans[1].Name != SpanExtension {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	if spans[1].Parent != spans[0] || !spans[0].Ended || !spans[1].Ended {
+		t.Errorf("unexpected span hierarchy %+v", spans)
+	}
+	expected := map[string]interface{}{
+		AttributeResource:  "articles",
+		AttributeFilters:   1,
+		AttributeFields:    "id, title",
+		AttributeStatement: "SELECT id, title FROM articles WHERE title = ?",
+	}
+	for key, value := range expected {
+		if got, _ := spans[0].Attribute(key); got != value {
+			t.Errorf("expected attribute %s %v, got %v", key, value, got)
+		}
+	}
+	if name, _ := spans[1].Attribute(AttributeExtension); name != "github.com/velmie/q2sql.failingExtension" {
+		t.Errorf("unexpected extension name %v", name)
+	}
+	if len(logger.records) != 1 {
+		t.Fatalf("expected 1 log record, got %d", len(logger.records))
+	}
+	if statement, _ := logger.records[0].value(AttributeStatement); statement != expected[AttributeStatement] {
+		t.Errorf("unexpected logged statement %v", statement)
+	}
+}
+
+func TestBuildTracingError(t *testing.T) {
+	tracer := &MemoryTracer{}
+	logger := &memoryLogger{}
+	query, err := qparser.ParseQuery("fail=1")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = newObservedBuilder(tracer, logger).Build(context.Background(), query); !errors.Is(err, errObserveExtension) {
+		t.Fatalf("expected extension error, got %v", err)
+	}
+	for _, span := range tracer.Spans() {
+		if len(span.Errors) != 1 || !errors.Is(span.Errors[0], errObserveExtension) {
+			t.Errorf("expected span %s to record the error, got %v", span.Name, span.Errors)
+		}
+	}
+	if _, ok := logger.records[0].value("error"); !ok {
+		t.Error("expected the error to be logged")
+	}
+}
+
+// renderCounter counts how many times the query is rendered
+type renderCounter struct {
+	calls int
+}
+
+func (c *renderCounter) ToSQL() (string, []interface{}, error) {
+	c.calls++
+	return "1 = 1", nil, nil
+}
+
+func TestBuildRendersOnce(t *testing.T) {
+	counter := new(renderCounter)
+	builder := newObservedBuilder(&MemoryTracer{}, &memoryLogger{},
+		WithLimits(Limits{MaxBoundParams: 10}),
+		Extend(func(_ context.Context, _ *qparser.Query, b *SelectBuilder) error {
+			b.Where(counter)
+			return nil
+		}),
+	)
+	query, err := qparser.ParseQuery("filter[title]=eq:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	ctx, trace := WithTrace(context.Background())
+	if _, err = builder.Build(ctx, query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if trace.SQL == "" {
+		t.Error("expected the trace to record the SQL")
+	}
+	// the limit check, the trace and the observers share the single rendering
+	if counter.calls != 1 {
+		t.Errorf("expected the query to be rendered once, got %d", counter.calls)
+	}
+}
+
+func TestExecutorTracing(t *testing.T) {
+	tracer := &MemoryTracer{}
+	logger := &memoryLogger{}
+	db, _ := openFakeDB(t, [][]driver.Value{{int64(1)}, {int64(2)}})
+	b := new(SelectBuilder).Select([]string{"id"}).From("articles")
+	executor := NewExecutor(db, WithExecutorTracer(tracer), WithExecutorLogger(logger))
+	_, err := executor.Select(context.Background(), b, func(rows *sql.Rows) error {
+		var id int64
+		return rows.Scan(&id)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	spans := tracer.Spans()
+	if len(spans) != 1 || spans[0].Name != SpanSelect || !spans[0].Ended {
+		t.Fatalf("unexpected spans %+v", spans)
+	}
+	if statement, _ := spans[0].Attribute(AttributeStatement); statement != "SELECT id FROM articles" {
+		t.Errorf("unexpected statement %v", statement)
+	}
+	if rows, _ := spans[0].Attribute(AttributeRows); rows != uint64(2) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+	if len(logger.records) != 1 || logger.records[0].msg != "q2sql: query executed" {
+		t.Errorf("unexpected log records %+v", logger.records)
+	}
+}
@@ -0,0 +1,353 @@
+package q2sql
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FieldType is the type of the field values, it is used in order to document the filters
+type FieldType string
+
+const (
+	FieldTypeString   FieldType = "string"
+	FieldTypeInteger  FieldType = "integer"
+	FieldTypeNumber   FieldType = "number"
+	FieldTypeBoolean  FieldType = "boolean"
+	FieldTypeDateTime FieldType = "date-time"
+)
+
+// valuePatterns are the regular expressions of the filter arguments by the field type
+var valuePatterns = map[FieldType]string{
+	FieldTypeInteger: `-?\d+`,
+	FieldTypeNumber:  `-?\d+(\.\d+)?`,
+	FieldTypeBoolean: `(true|false)`,
+}
+
+// relativeTimeDescription describes the values of the fields set by WithRelativeTimeFilter
+const relativeTimeDescription = "Time expression: RFC 3339 date-time or date, or a keyword " +
+	"(now, today, yesterday, tomorrow, startOfHour, startOfDay, startOfWeek, startOfMonth, startOfYear) " +
+	"followed by offsets, e.g. now-7d, startOfMonth-1mo"
+
+// PageParameter describes the "page[name]" query parameter handled by the pagination extension,
+// the builder knows nothing about the extensions so the parameters are documented by the extension
+// which is added by ExtendPagination (see the pagination options of the extension package)
+type PageParameter struct {
+	Name string    `json:"name"`
+	Type FieldType `json:"type,omitempty"`
+	// Maximum is the maximal allowed value, zero means there is no maximum
+	Maximum int64 `json:"maximum,omitempty"`
+}
+
+// JSONSchema is the subset of JSON Schema used in order to describe the query parameters
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"` //nolint:tagliatelle // JSON Schema keyword
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *int64                 `json:"minimum,omitempty"`
+	Maximum              *int64                 `json:"maximum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	UniqueItems          bool                   `json:"uniqueItems,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// OpenAPIParameter is OpenAPI 3 parameter object of the query string.
+// The filters are additionally described by the "x-conditions" and "x-value-schema" extensions
+type OpenAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Style       string      `json:"style,omitempty"`
+	Explode     *bool       `json:"explode,omitempty"`
+	Schema      *JSONSchema `json:"schema"`
+	Conditions  []string    `json:"x-conditions,omitempty"`   //nolint:tagliatelle // OpenAPI extension
+	ValueSchema *JSONSchema `json:"x-value-schema,omitempty"` //nolint:tagliatelle // OpenAPI extension
+}
+
+// OpenAPIParameters describes the query parameters accepted by the builder: "fields[resource]",
+// "filter[field]" for each filtered field, "sort", the aggregation parameters if AllowAggregation is used
+// and "page[...]" documented by the pagination extension added by ExtendPagination.
+// The allowed values of "fields[resource]" and "sort" are listed by API names, the columns are translated
+// back by the reverse translator (see WithReverseTranslator); if it is not set or some column cannot
+// be translated, the values are not listed since the column names are not accepted by the builder
+func (s *ResourceSelectBuilder) OpenAPIParameters() []OpenAPIParameter {
+	parameters := make([]OpenAPIParameter, 0, len(s.allowedConditions)+len(s.pageParameters)+2) //nolint:gomnd // fields and sort
+	if fields, ok := s.selectableNames(); len(fields) > 0 || !ok {
+		parameters = append(parameters, listParameter(
+			fieldsParameter+"["+s.resourceName+"]",
+			"Comma separated list of the fields to select",
+			fields,
+			ok,
+		))
+	}
+	for _, field := range sortedKeys(s.allowedConditions) {
+		conditions := append([]string(nil), s.allowedConditions[field]...)
+		sort.Strings(conditions)
+		parameters = append(parameters, OpenAPIParameter{
+			Name:        filterParameter + "[" + field + "]",
+			In:          "query",
+			Description: "Filter by " + field + ", conditions: " + strings.Join(conditions, ", "),
+			Schema:      s.conditionSchema(conditions, s.valueType(field)),
+			Conditions:  conditions,
+			ValueSchema: s.valueSchema(field),
+		})
+	}
+	if names, ok := s.sortableNames(); len(names) > 0 || !ok {
+		parameters = append(parameters, listParameter(
+			sortParameter,
+			"Comma separated list of the sort fields, prefix \"-\" means descending order",
+			sortValues(names),
+			ok,
+		))
+	}
+	parameters = append(parameters, s.aggregationParameters()...)
+	for _, page := range s.pageParameters {
+		parameters = append(parameters, OpenAPIParameter{
+			Name:   pageParameter + "[" + page.Name + "]",
+			In:     "query",
+			Schema: page.schema(),
+		})
+	}
+	return parameters
+}
+
+// aggregationParameters describes "group", "aggregate" and "having[alias]" parameters
+func (s *ResourceSelectBuilder) aggregationParameters() []OpenAPIParameter {
+	if s.aggregation == nil {
+		return nil
+	}
+	var parameters []OpenAPIParameter
+	if len(s.aggregation.GroupFields) > 0 {
+		groups := append([]string(nil), s.aggregation.GroupFields...)
+		sort.Strings(groups)
+		parameters = append(parameters, listParameter(
+			GroupParameter,
+			"Comma separated list of the fields to group by",
+			groups,
+			true,
+		))
+	}
+	aggregates, aliases := s.aggregateNames()
+	if len(aggregates) == 0 {
+		return parameters
+	}
+	parameters = append(parameters, listParameter(
+		AggregateParameter,
+		"Comma separated list of the aggregates in the form function(field), "+
+			"the result is named function_field (count for count(*))",
+		aggregates,
+		true,
+	))
+	if len(s.aggregation.HavingConditions) == 0 {
+		return parameters
+	}
+	conditions := append([]string(nil), s.aggregation.HavingConditions...)
+	sort.Strings(conditions)
+	for _, alias := range aliases {
+		parameters = append(parameters, OpenAPIParameter{
+			Name:        HavingParameter + "[" + alias + "]",
+			In:          "query",
+			Description: "Filter by the aggregate " + alias + ", conditions: " + strings.Join(conditions, ", "),
+			Schema:      s.conditionSchema(conditions, FieldTypeNumber),
+			Conditions:  conditions,
+			ValueSchema: fieldTypeSchema(FieldTypeNumber),
+		})
+	}
+	return parameters
+}
+
+// aggregateNames returns the allowed "function(field)" expressions and the names of their results
+func (s *ResourceSelectBuilder) aggregateNames() (aggregates, aliases []string) {
+	for field, functions := range s.aggregation.Aggregates {
+		for _, function := range functions {
+			if _, ok := aggregateFunctions[function]; !ok {
+				continue
+			}
+			aggregates = append(aggregates, function+"("+field+")")
+			if field == CountAll {
+				aliases = append(aliases, function)
+			} else {
+				aliases = append(aliases, function+"_"+field)
+			}
+		}
+	}
+	sort.Strings(aggregates)
+	sort.Strings(aliases)
+	return aggregates, aliases
+}
+
+func listParameter(name, description string, values []string, listed bool) OpenAPIParameter {
+	explode := false
+	items := &JSONSchema{Type: "string", Enum: values}
+	if !listed {
+		items.Enum = nil
+		description += ", the allowed values are not listed since their API names are unknown"
+	}
+	return OpenAPIParameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Style:       "form",
+		Explode:     &explode,
+		Schema:      &JSONSchema{Type: "array", Items: items, UniqueItems: true},
+	}
+}
+
+// JSONSchema describes the query parameters as the object which properties are the parameter names,
+// the values are the same as in OpenAPIParameters, i.e. "fields[...]" and "sort" are arrays.
+// Additional properties are allowed, since the sort expressions and the extensions
+// could accept the parameters which are not described (e.g. "origin" of geo.DistanceSort)
+func (s *ResourceSelectBuilder) JSONSchema() *JSONSchema {
+	schema := &JSONSchema{
+		Schema:      "https://json-schema.org/draft/2020-12/schema",
+		Type:        "object",
+		Description: "Query parameters of the " + s.resourceName + " resource",
+		Properties:  make(map[string]*JSONSchema),
+	}
+	for _, parameter := range s.OpenAPIParameters() {
+		property := *parameter.Schema
+		if property.Description == "" {
+			property.Description = parameter.Description
+		}
+		schema.Properties[parameter.Name] = &property
+	}
+	return schema
+}
+
+// conditionSchema describes the filter expression, the pattern is known only for DelimitedArgsParser
+func (s *ResourceSelectBuilder) conditionSchema(conditions []string, valueType FieldType) *JSONSchema {
+	schema := &JSONSchema{Type: "string"}
+	parser, ok := s.parser.(*DelimitedArgsParser)
+	if !ok {
+		return schema
+	}
+	names := make([]string, len(conditions))
+	for i, condition := range conditions {
+		names[i] = regexp.QuoteMeta(condition)
+	}
+	value, ok := valuePatterns[valueType]
+	if !ok {
+		value = "[^" + regexp.QuoteMeta(parser.argsDelim) + "]*"
+	}
+	mainDelim := regexp.QuoteMeta(string(parser.mainDelim))
+	argsDelim := regexp.QuoteMeta(parser.argsDelim)
+	schema.Pattern = "^(" + strings.Join(names, "|") + ")(" + mainDelim + value + "(" + argsDelim + value + ")*)?$"
+	return schema
+}
+
+// valueType returns the type of the filter values, the values of the fields with ArgsResolver
+// are converted by the resolver, so they are documented as strings
+func (s *ResourceSelectBuilder) valueType(field string) FieldType {
+	if _, ok := s.argsResolvers[field]; ok {
+		return FieldTypeString
+	}
+	return s.fieldTypes[field]
+}
+
+func (s *ResourceSelectBuilder) valueSchema(field string) *JSONSchema {
+	if _, ok := s.relativeTimeFields[field]; ok {
+		return &JSONSchema{Type: string(FieldTypeString), Description: relativeTimeDescription}
+	}
+	return fieldTypeSchema(s.valueType(field))
+}
+
+func sortValues(names []string) []string {
+	values := make([]string, 0, len(names)*2) //nolint:gomnd // ascending and descending
+	for _, name := range names {
+		values = append(values, name, "-"+name)
+	}
+	return values
+}
+
+func (p PageParameter) schema() *JSONSchema {
+	schema := fieldTypeSchema(p.Type)
+	if p.Type == FieldTypeInteger || p.Type == FieldTypeNumber {
+		minimum := int64(0)
+		schema.Minimum = &minimum
+		if p.Maximum > 0 {
+			maximum := p.Maximum
+			schema.Maximum = &maximum
+		}
+	}
+	return schema
+}
+
+func fieldTypeSchema(t FieldType) *JSONSchema {
+	switch t {
+	case "":
+		return &JSONSchema{Type: string(FieldTypeString)}
+	case FieldTypeDateTime:
+		return &JSONSchema{Type: string(FieldTypeString), Format: string(FieldTypeDateTime)}
+	default:
+		return &JSONSchema{Type: string(t)}
+	}
+}
+
+// selectableNames returns API names of the fields allowed for selection,
+// ok is false if some column cannot be translated back to API name
+func (s *ResourceSelectBuilder) selectableNames() (names []string, ok bool) {
+	names = make([]string, 0, len(s.allowedSelectFieldsSlc)+len(s.computedFields))
+	ok = true
+	for _, column := range s.allowedSelectFieldsSlc {
+		if column == "*" {
+			continue
+		}
+		name, translated := s.apiName(column)
+		ok = ok && translated
+		names = append(names, name)
+	}
+	for name := range s.computedFields {
+		names = append(names, name)
+	}
+	names = removeDuplicateStrings(names)
+	sort.Strings(names)
+	return names, ok
+}
+
+// sortableNames returns API names of the fields allowed for sorting, see selectableNames
+func (s *ResourceSelectBuilder) sortableNames() (names []string, ok bool) {
+	names = make([]string, 0, len(s.allowedSortFields)+len(s.sortExpressions))
+	ok = true
+	for _, column := range s.allowedSortFields {
+		name, translated := s.apiName(column)
+		ok = ok && translated
+		names = append(names, name)
+	}
+	for name := range s.sortExpressions {
+		names = append(names, name)
+	}
+	for name, computed := range s.computedFields {
+		if computed.Sortable {
+			names = append(names, name)
+		}
+	}
+	names = removeDuplicateStrings(names)
+	sort.Strings(names)
+	return names, ok
+}
+
+// apiName translates the column back to API name, the column itself is returned
+// with false if the reverse translator is not set or fails
+func (s *ResourceSelectBuilder) apiName(column string) (string, bool) {
+	if s.reverseTranslator == nil {
+		return column, false
+	}
+	names, err := s.reverseTranslator([]string{column})
+	if err != nil || len(names) != 1 {
+		return column, false
+	}
+	return names[0], true
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
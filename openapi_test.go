@@ -0,0 +1,193 @@
+package q2sql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/velmie/qparser"
+)
+
+// pageLimit stands for the pagination extension documented by ExtendPagination
+func pageLimit(context.Context, *qparser.Query, *SelectBuilder) error { return nil }
+
+func newOpenAPITestBuilder(t *testing.T, options ...ResourceSelectBuilderOption) *ResourceSelectBuilder {
+	t.Helper()
+	translator, err := NewBidirectionalTranslator(map[string]string{
+		"id":        "id",
+		"title":     "title",
+		"createdAt": "created_at",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newArticlesBuilder(append([]ResourceSelectBuilderOption{
+		WithBidirectionalTranslator(translator),
+		AllowSelectFields([]string{"*", "id", "title", "created_at"}),
+		AllowFiltering(
+			AllowedConditions{"id": {filterEq, filterAny}, "createdAt": {"gt"}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithFieldTypes(map[string]FieldType{"id": FieldTypeInteger, "createdAt": FieldTypeDateTime}),
+		AllowSortingByExpressions(map[string]SortExpression{"relevance": nil}),
+		ExtendPagination(pageLimit, PageParameter{Name: "limit", Type: FieldTypeInteger, Maximum: 100}),
+	}, options...)...)
+}
+
+func TestOpenAPIParameters(t *testing.T) {
+	parameters := newOpenAPITestBuilder(t).OpenAPIParameters()
+	names := make([]string, len(parameters))
+	for i, p := range parameters {
+		names[i] = p.Name
+	}
+	wantNames := []string{"fields[articles]", "filter[createdAt]", "filter[id]", "sort", "page[limit]"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("expected parameters %v, got %v", wantNames, names)
+	}
+
+	if got, want := parameters[0].Schema.Items.Enum, []string{"createdAt", "id", "title"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fields %v, got %v", want, got)
+	}
+	if got, want := parameters[3].Schema.Items.Enum, []string{"createdAt", "-createdAt", "relevance", "-relevance"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected sort values %v, got %v", want, got)
+	}
+	if got := parameters[4].Schema; got.Maximum == nil || *got.Maximum != 100 || got.Type != "integer" {
+		t.Errorf("unexpected page schema %+v", got)
+	}
+
+	id := parameters[2]
+	if !reflect.DeepEqual(id.Conditions, []string{filterAny, filterEq}) {
+		t.Errorf("unexpected conditions %v", id.Conditions)
+	}
+	if id.ValueSchema.Type != "integer" {
+		t.Errorf("unexpected value schema %+v", id.ValueSchema)
+	}
+	pattern := regexp.MustCompile(id.Schema.Pattern)
+	for value, match := range map[string]bool{
+		"eq:42":    true,
+		"any:1,-2": true,
+		"eq":       true,
+		"eq:abc":   false,
+		"like:1":   false,
+	} {
+		if pattern.MatchString(value) != match {
+			t.Errorf("pattern %q: expected match of %q to be %v", id.Schema.Pattern, value, match)
+		}
+	}
+	if createdAt := parameters[1].ValueSchema; createdAt.Format != "date-time" {
+		t.Errorf("unexpected value schema %+v", createdAt)
+	}
+}
+
+func TestOpenAPIParametersWithoutReverseTranslator(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		"articles",
+		MapTranslator(map[string]string{"createdAt": "created_at"}),
+		AllowSortingByFields([]string{"created_at"}),
+		AllowSortingByExpressions(map[string]SortExpression{"relevance": nil}),
+	)
+	parameters := builder.OpenAPIParameters()
+	if len(parameters) != 1 || parameters[0].Name != "sort" {
+		t.Fatalf("unexpected parameters %+v", parameters)
+	}
+	// the column names are not accepted by the builder, so the values are not listed at all
+	if got := parameters[0].Schema.Items.Enum; got != nil {
+		t.Errorf("expected the sort values not to be listed, got %v", got)
+	}
+	if !strings.Contains(parameters[0].Description, "not listed") {
+		t.Errorf("unexpected description %q", parameters[0].Description)
+	}
+}
+
+func TestOpenAPIAggregationParameters(t *testing.T) {
+	builder := newOpenAPITestBuilder(t, AllowAggregation(Aggregation{
+		GroupFields:      []string{"title"},
+		Aggregates:       AllowedAggregates{CountAll: {"count"}, "id": {"max"}},
+		HavingConditions: []string{filterEq, "gt"},
+	}))
+	parameters := make(map[string]OpenAPIParameter)
+	for _, p := range builder.OpenAPIParameters() {
+		parameters[p.Name] = p
+	}
+	if got := parameters[GroupParameter].Schema.Items.Enum; !reflect.DeepEqual(got, []string{"title"}) {
+		t.Errorf("unexpected group values %v", got)
+	}
+	if got := parameters[AggregateParameter].Schema.Items.Enum; !reflect.DeepEqual(got, []string{"count(*)", "max(id)"}) {
+		t.Errorf("unexpected aggregate values %v", got)
+	}
+	for _, name := range []string{"having[count]", "having[max_id]"} {
+		having, ok := parameters[name]
+		if !ok {
+			t.Fatalf("expected %s to be documented", name)
+		}
+		if !reflect.DeepEqual(having.Conditions, []string{filterEq, "gt"}) {
+			t.Errorf("%s: unexpected conditions %v", name, having.Conditions)
+		}
+		if !regexp.MustCompile(having.Schema.Pattern).MatchString("gt:10.5") {
+			t.Errorf("%s: expected pattern %q to match the number", name, having.Schema.Pattern)
+		}
+	}
+}
+
+func TestOpenAPIRelativeTimeParameters(t *testing.T) {
+	builder := newOpenAPITestBuilder(t, WithRelativeTimeFilter("createdAt", time.UTC))
+	var createdAt OpenAPIParameter
+	for _, p := range builder.OpenAPIParameters() {
+		if p.Name == "filter[createdAt]" {
+			createdAt = p
+		}
+	}
+	if createdAt.ValueSchema.Format != "" || createdAt.ValueSchema.Description != relativeTimeDescription {
+		t.Errorf("expected relative time value schema, got %+v", createdAt.ValueSchema)
+	}
+	if !regexp.MustCompile(createdAt.Schema.Pattern).MatchString("gt:now-7d") {
+		t.Errorf("expected pattern %q to match the relative time", createdAt.Schema.Pattern)
+	}
+	if fields := builder.Describe().Fields; !fields[0].RelativeTime {
+		t.Errorf("expected createdAt to be described as relative time, got %+v", fields[0])
+	}
+
+	// the values of the field with a resolver are converted by it, so the type is not documented
+	resolver := func(_ context.Context, args []string) ([]interface{}, error) { return nil, nil }
+	builder = newOpenAPITestBuilder(t, WithFilterArgsResolver("id", resolver))
+	for _, p := range builder.OpenAPIParameters() {
+		if p.Name == "filter[id]" && (p.ValueSchema.Type != "string" || !regexp.MustCompile(p.Schema.Pattern).MatchString("eq:abc")) {
+			t.Errorf("expected string values, got %+v %+v", p.ValueSchema, p.Schema)
+		}
+	}
+}
+
+func TestOpenAPIParameterJSON(t *testing.T) {
+	parameters := newOpenAPITestBuilder(t).OpenAPIParameters()
+	data, err := json.Marshal(parameters[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"filter[id]","in":"query","description":"Filter by id, conditions: any, eq",` +
+		`"schema":{"type":"string","pattern":"^(any|eq)(:-?\\d+(,-?\\d+)*)?$"},` +
+		`"x-conditions":["any","eq"],"x-value-schema":{"type":"integer"}}`
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := newOpenAPITestBuilder(t).JSONSchema()
+	if schema.Type != "object" || schema.AdditionalProperties != nil {
+		t.Errorf("unexpected schema %+v", schema)
+	}
+	if len(schema.Properties) != 5 {
+		t.Fatalf("expected 5 properties, got %d", len(schema.Properties))
+	}
+	if sort := schema.Properties["sort"]; sort.Type != "array" || sort.Description == "" {
+		t.Errorf("unexpected sort property %+v", sort)
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
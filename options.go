@@ -1,5 +1,7 @@
 package q2sql
 
+import "time"
+
 type ResourceSelectBuilderOption func(b *ResourceSelectBuilder)
 
 // WithDefaultFields sets default fields which are used in the SELECT
@@ -41,6 +43,119 @@ func AllowSortingByFields(fields []string) ResourceSelectBuilderOption {
 	}
 }
 
+// AllowSortingByExpressions allows to sort by pseudo-fields which are not translated
+// but replaced with the corresponding expressions in the "ORDER BY" SQL statement.
+// The option could be applied multiple times.
+func AllowSortingByExpressions(expressions map[string]SortExpression) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		if b.sortExpressions == nil {
+			b.sortExpressions = make(map[string]SortExpression, len(expressions))
+		}
+		for name, expression := range expressions {
+			b.sortExpressions[name] = expression
+		}
+	}
+}
+
+// WithPolicy sets the policy which narrows allowed select fields, sort fields and conditions
+// for each request at the build time
+func WithPolicy(policy Policy) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.policy = policy
+	}
+}
+
+// WithLimits restricts complexity of the query,
+// *LimitError is returned by the Build method if the query exceeds the limits
+func WithLimits(limits Limits) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.limits = &limits
+	}
+}
+
+// WithCommonTableExpressions adds common table expressions to the built query,
+// the resource name could refer to one of them in order to select from the expression
+func WithCommonTableExpressions(ctes ...CTE) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.ctes = append(b.ctes, ctes...)
+	}
+}
+
+// WithComputedFields exposes SQL expressions such as window functions as the fields,
+// the fields could be requested by means of the "fields[resource]" parameter
+// and used for sorting if they are sortable.
+// The map key is the field name which is used as the expression alias
+func WithComputedFields(fields map[string]ComputedField) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.computedFields = fields
+	}
+}
+
+// WithDialect sets the dialect of the built select builders,
+// it is used in order to validate dialect specific clauses such as row locking
+func WithDialect(dialect Dialect) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.dialect = dialect
+	}
+}
+
+// WithReverseTranslator sets translator from the columns to API names, it is used in order to report
+// the columns by the client-facing names in Describe, OpenAPIParameters and the errors caused by
+// the default fields. The errors caused by the query always refer to the names used in the query
+func WithReverseTranslator(reverse Translator) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.reverseTranslator = reverse
+	}
+}
+
+// WithBidirectionalTranslator sets both the translator and the reverse translator,
+// the translator passed to the constructor is replaced
+func WithBidirectionalTranslator(t *BidirectionalTranslator) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.translator = t.Translate
+		b.reverseTranslator = t.Reverse
+	}
+}
+
+// WithTracer reports spans of the Build and the extensions to the tracer
+func WithTracer(tracer Tracer) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.tracer = tracer
+	}
+}
+
+// WithLogger reports built queries to the logger at the debug level, *slog.Logger could be used
+func WithLogger(logger Logger) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.logger = logger
+	}
+}
+
+// WithPlanCache enables LRU cache of the given size which keeps the results of the translation
+// and the allow-list checks for the repeated shapes of the query: the selected fields, the filter
+// fields with the condition names and the number of the arguments, the sort fields.
+// The plan also keeps the rendered WHERE clause of the filters, the arguments of the request are bound to it,
+// so the conditions must render the same SQL for the same number of arguments. The conditions are still
+// created in order to validate the arguments, the template is not used if they do not take the arguments as is
+// (e.g. the time bucket is expanded). The policy and the extensions are applied on every request.
+// The cache is bypassed if the trace is active or the aggregation is requested
+func WithPlanCache(size int) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		if size > 0 {
+			b.plans = newPlanCache(size)
+		}
+	}
+}
+
+// AllowAggregation allows the client to request grouping and aggregation
+// by means of the "group", "aggregate" and "having[...]" query parameters,
+// the selected fields are replaced with the group fields and the aggregates when requested
+func AllowAggregation(aggregation Aggregation) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.aggregation = &aggregation
+	}
+}
+
 // Extend adds Extensions to the list
 func Extend(extensions ...Extension) ResourceSelectBuilderOption {
 	return func(b *ResourceSelectBuilder) {
@@ -64,3 +179,52 @@ func AlwaysSelectAllFields(flag bool) ResourceSelectBuilderOption {
 		b.alwaysSelectAllFields = flag
 	}
 }
+
+// WithFilterArgsResolver sets the resolver which converts arguments of the filters
+// applied to the given field, for example RelativeTimeArgs allows using expressions
+// like "filter[createdAt]=gt:now-7d"
+func WithFilterArgsResolver(field string, resolver ArgsResolver) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		if b.argsResolvers == nil {
+			b.argsResolvers = make(map[string]ArgsResolver)
+		}
+		b.argsResolvers[field] = resolver
+		delete(b.relativeTimeFields, field)
+	}
+}
+
+// WithRelativeTimeFilter sets RelativeTimeArgs as the resolver of the filters applied to the given field,
+// the field values are documented as the relative time expressions instead of the date-time (see OpenAPIParameters)
+func WithRelativeTimeFilter(field string, loc *time.Location) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		WithFilterArgsResolver(field, RelativeTimeArgs(loc))(b)
+		if b.relativeTimeFields == nil {
+			b.relativeTimeFields = make(map[string]struct{})
+		}
+		b.relativeTimeFields[field] = struct{}{}
+	}
+}
+
+// WithFieldTypes sets types of the fields (by API names) which are used in order to document
+// the filter values, see OpenAPIParameters. The fields are considered as strings by default
+func WithFieldTypes(types map[string]FieldType) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		if b.fieldTypes == nil {
+			b.fieldTypes = make(map[string]FieldType, len(types))
+		}
+		for field, t := range types {
+			b.fieldTypes[field] = t
+		}
+	}
+}
+
+// ExtendPagination adds the pagination extension together with the description of the "page[...]"
+// parameters it handles, see OpenAPIParameters. The pagination options of the extension package
+// (e.g. extension.WithLimitOffsetPagination) use it, so the documented limits are taken from
+// the same values the extension checks
+func ExtendPagination(extension Extension, parameters ...PageParameter) ResourceSelectBuilderOption {
+	return func(b *ResourceSelectBuilder) {
+		b.extensions = append(b.extensions, extension)
+		b.pageParameters = append(b.pageParameters, parameters...)
+	}
+}
@@ -0,0 +1,45 @@
+package q2sql
+
+// PageInfo describes the pagination applied to the select builder,
+// it is recorded by pagination extensions so that handlers could build response metadata
+type PageInfo struct {
+	// Limit is the page size, zero means no limit
+	Limit uint64 `json:"limit"`
+	// Offset is the number of skipped records
+	Offset uint64 `json:"offset"`
+	// Number is the page number starting from 1, zero if the pagination is not page number based
+	Number uint64 `json:"number,omitempty"`
+	// Cursor is the page cursor given in the query
+	Cursor string `json:"cursor,omitempty"`
+	// DefaultApplied indicates that the limit is not requested and the default one is applied
+	DefaultApplied bool `json:"defaultApplied"`
+	// FetchExtra indicates that the SQL limit is greater than the Limit by one record,
+	// the extra record is used to detect if the next page exists, see TrimPage
+	FetchExtra bool `json:"-"`
+}
+
+// TotalPages calculates the number of pages for the total number of records
+func (p *PageInfo) TotalPages(total uint64) uint64 {
+	if total == 0 {
+		return 0
+	}
+	if p.Limit == 0 {
+		return 1
+	}
+	return (total + p.Limit - 1) / p.Limit
+}
+
+// HasNext reports whether there are records after the current page
+func (p *PageInfo) HasNext(total uint64) bool {
+	return p.Limit > 0 && p.Offset+p.Limit < total
+}
+
+// TrimPage trims the extra record fetched in case if the page info has the FetchExtra flag
+// and reports whether the next page exists. If the flag is not set the records are returned as is
+// and hasNext is false because it cannot be detected
+func TrimPage[T any](records []T, page *PageInfo) (trimmed []T, hasNext bool) {
+	if page == nil || !page.FetchExtra || uint64(len(records)) <= page.Limit {
+		return records, false
+	}
+	return records[:page.Limit], true
+}
@@ -0,0 +1,34 @@
+package q2sql
+
+import (
+	"fmt"
+	"testing"
+)
+
+type pageInfoTest struct {
+	info       PageInfo
+	total      uint64
+	totalPages uint64
+	hasNext    bool
+}
+
+var pageInfoTests = []pageInfoTest{
+	{info: PageInfo{Limit: 10}, total: 0, totalPages: 0, hasNext: false},
+	{info: PageInfo{Limit: 10}, total: 10, totalPages: 1, hasNext: false},
+	{info: PageInfo{Limit: 10}, total: 11, totalPages: 2, hasNext: true},
+	{info: PageInfo{Limit: 10, Offset: 10}, total: 20, totalPages: 2, hasNext: false},
+	{info: PageInfo{Limit: 10, Offset: 10}, total: 21, totalPages: 3, hasNext: true},
+	{info: PageInfo{}, total: 21, totalPages: 1, hasNext: false},
+}
+
+func TestPageInfo(t *testing.T) {
+	for i, tt := range pageInfoTests {
+		meta := fmt.Sprintf("test %d", i)
+		if got := tt.info.TotalPages(tt.total); got != tt.totalPages {
+			t.Errorf("%s: expected total pages %d, got %d", meta, tt.totalPages, got)
+		}
+		if got := tt.info.HasNext(tt.total); got != tt.hasNext {
+			t.Errorf("%s: expected has next %v, got %v", meta, tt.hasNext, got)
+		}
+	}
+}
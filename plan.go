@@ -0,0 +1,356 @@
+package q2sql
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/velmie/qparser"
+)
+
+// PlanCacheStats reports the usage of the plan cache
+type PlanCacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+// queryPlan is the result of the translation and the allow-list checks
+// which depend only on the shape of the query: the selected fields, the filter fields
+// with the condition names and the number of the arguments and the sort fields.
+// The WHERE clause of the filters is kept as the SQL template if it could be, the arguments are bound
+// to it on every request, the policy checks and the extensions are also applied on every request
+type queryPlan struct {
+	selectCached bool
+	selectFields []string
+	computed     []Sqlizer
+	filters      []plannedFilter
+	sorts        []plannedSort
+	where        *filterTemplate
+}
+
+// filterTemplate is the rendered SQL of the filter conditions joined by "AND",
+// args is the number of the arguments of each filter
+type filterTemplate struct {
+	sql  string
+	args []int
+	size int
+}
+
+// filterValues are the values the filter condition is created from,
+// they cannot be bound to the template if the condition is not created from them as is
+// (e.g. the time bucket is expanded into the range)
+type filterValues struct {
+	values   []interface{}
+	bindable bool
+}
+
+type plannedFilter struct {
+	column    string
+	condition Condition
+}
+
+type plannedSort struct {
+	column     string
+	computed   Sqlizer
+	expression SortExpression
+}
+
+type parsedFilter struct {
+	name string
+	args []string
+}
+
+// planRequest is the plan used by the single Build
+type planRequest struct {
+	key      string
+	plan     *queryPlan
+	compiled bool
+	filters  []parsedFilter
+}
+
+// planCache is LRU cache of the query plans
+type planCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	stats    PlanCacheStats
+}
+
+type planEntry struct {
+	key  string
+	plan *queryPlan
+}
+
+func newPlanCache(capacity int) *planCache {
+	return &planCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *planCache) get(key string) (*queryPlan, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*planEntry).plan, true
+}
+
+func (c *planCache) add(key string, plan *queryPlan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[key]; ok {
+		element.Value.(*planEntry).plan = plan
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&planEntry{key: key, plan: plan})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*planEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *planCache) snapshot() PlanCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// PlanCacheStats returns the statistics of the plan cache enabled by the WithPlanCache option
+func (s *ResourceSelectBuilder) PlanCacheStats() PlanCacheStats {
+	if s.plans == nil {
+		return PlanCacheStats{}
+	}
+	return s.plans.snapshot()
+}
+
+// planFor returns the plan of the query, nil is returned if the cache is not used:
+// it is not enabled, the trace is active, the aggregation is requested or the query is invalid
+func (s *ResourceSelectBuilder) planFor(ctx context.Context, query *qparser.Query) *planRequest {
+	if s.plans == nil || TraceFromContext(ctx) != nil {
+		return nil
+	}
+	if s.aggregation != nil && (query.Values.Get(GroupParameter) != "" || query.Values.Get(AggregateParameter) != "") {
+		return nil
+	}
+	r := &planRequest{filters: make([]parsedFilter, len(query.Filters))}
+	if len(query.Filters) > 0 && s.parser == nil {
+		return nil
+	}
+	key := &strings.Builder{}
+	if fields, ok := query.Fields.FieldsByResource(s.resourceName); ok {
+		key.WriteString("f")
+		writePlanKeyParts(key, fields...)
+	}
+	for i, filter := range query.Filters {
+		name, args, err := s.parser.ParseFilterExpression(filter.Predicate)
+		if err != nil {
+			return nil
+		}
+		r.filters[i] = parsedFilter{name: name, args: args}
+		key.WriteString("w")
+		writePlanKeyParts(key, filter.FieldName, name, strconv.Itoa(len(args)))
+	}
+	for _, sort := range query.Sort {
+		key.WriteString("s")
+		writePlanKeyParts(key, encodeSort(sort))
+	}
+	r.key = key.String()
+	if plan, ok := s.plans.get(r.key); ok {
+		r.plan, r.compiled = plan, true
+		return r
+	}
+	r.plan = &queryPlan{}
+	return r
+}
+
+// writePlanKeyParts writes length prefixed parts, so the client cannot forge the key of another shape
+func writePlanKeyParts(key *strings.Builder, parts ...string) {
+	for _, part := range parts {
+		key.WriteString(strconv.Itoa(len(part)))
+		key.WriteByte(':')
+		key.WriteString(part)
+	}
+}
+
+// store saves the plan built by the successful Build
+func (s *ResourceSelectBuilder) store(r *planRequest) {
+	if r == nil || r.compiled {
+		return
+	}
+	s.plans.add(r.key, r.plan)
+}
+
+// parsedFilter returns the filter expression parsed by the planFor or parses it
+func (r *planRequest) parsedFilter(s *ResourceSelectBuilder, i int, filter qparser.Filter) (string, []string, error) {
+	if r != nil {
+		return r.filters[i].name, r.filters[i].args, nil
+	}
+	return s.parser.ParseFilterExpression(filter.Predicate)
+}
+
+// bindFilters returns the cached SQL template of the filters bound to the values of the request.
+// On the miss the template is created from the conditions, the conditions are returned as is
+// if there is no template or the values cannot be bound to it
+func (r *planRequest) bindFilters(conditions []Sqlizer, values []filterValues) []Sqlizer {
+	if r == nil || len(conditions) == 0 {
+		return conditions
+	}
+	if !r.compiled {
+		r.plan.where = newFilterTemplate(conditions, values)
+		return conditions
+	}
+	t := r.plan.where
+	if t == nil {
+		return conditions
+	}
+	args := make([]interface{}, 0, t.size)
+	for i, v := range values {
+		if !v.bindable || len(v.values) != t.args[i] {
+			return conditions
+		}
+		args = append(args, v.values...)
+	}
+	return []Sqlizer{&RawSQLWithArgs{SQL: t.sql, Args: args}}
+}
+
+// newFilterTemplate renders the filter conditions into the template. Nil is returned unless every condition
+// takes its values as the arguments in the same order, so the values of another request could replace them,
+// the values must be distinct, otherwise their order in the arguments cannot be told
+func newFilterTemplate(conditions []Sqlizer, values []filterValues) *filterTemplate {
+	t := &filterTemplate{args: make([]int, len(conditions))}
+	for i, cond := range conditions {
+		if !values[i].bindable || !distinctValues(values[i].values) {
+			return nil
+		}
+		_, args, err := cond.ToSQL()
+		if err != nil || !sameValues(args, values[i].values) {
+			return nil
+		}
+		t.args[i] = len(args)
+		t.size += len(args)
+	}
+	sql := getBuffer()
+	defer putBuffer(sql)
+	if _, err := appendToSQL(conditions, sql, " AND ", nil); err != nil || sql.Len() == 0 {
+		return nil
+	}
+	t.sql = sql.String()
+	return t
+}
+
+func sameValues(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func distinctValues(values []interface{}) bool {
+	for i := range values {
+		for j := i + 1; j < len(values); j++ {
+			if reflect.DeepEqual(values[i], values[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compiledFilter returns the cached filter or compiles it
+func (s *ResourceSelectBuilder) compiledFilter(r *planRequest, i int, field, name string) (*plannedFilter, error) {
+	if r != nil && r.compiled {
+		return &r.plan.filters[i], nil
+	}
+	f, err := s.compileFilter(field, name)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		r.plan.filters = append(r.plan.filters, *f)
+	}
+	return f, nil
+}
+
+// compileFilter translates the field and checks the allow-list
+func (s *ResourceSelectBuilder) compileFilter(field, name string) (*plannedFilter, error) {
+	allowList, ok := s.allowedConditionsSet[field]
+	if !ok {
+		return nil, &FilterError{
+			Field:   field,
+			Message: fmt.Sprintf("filters cannot be applied to the field %q", field),
+		}
+	}
+	columns, err := s.translator([]string{field})
+	if err != nil {
+		return nil, err
+	}
+	if _, allowed := allowList[name]; !allowed {
+		return nil, &FilterError{
+			Filter:  name,
+			Field:   field,
+			Message: fmt.Sprintf("filter %q cannot be applied to the field %q", name, field),
+		}
+	}
+	condition, err := s.conditions.CreateCondition(name)
+	if err != nil {
+		return nil, err
+	}
+	return &plannedFilter{column: columns[0], condition: condition}, nil
+}
+
+// compiledSort returns the cached sort or compiles it
+func (s *ResourceSelectBuilder) compiledSort(r *planRequest, i int, field string) (*plannedSort, error) {
+	if r != nil && r.compiled {
+		return &r.plan.sorts[i], nil
+	}
+	sort, err := s.compileSort(field)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		r.plan.sorts = append(r.plan.sorts, *sort)
+	}
+	return sort, nil
+}
+
+// compileSort resolves the computed field, the sort expression or translates the field and checks the allow-list
+func (s *ResourceSelectBuilder) compileSort(field string) (*plannedSort, error) {
+	if computed, ok := s.computedFields[field]; ok && computed.Sortable {
+		return &plannedSort{computed: computed.Expr}, nil
+	}
+	if expression, ok := s.sortExpressions[field]; ok {
+		return &plannedSort{expression: expression}, nil
+	}
+	columns, err := s.translator([]string{field})
+	if err != nil {
+		return nil, err
+	}
+	if _, allowed := s.allowedSortFieldsSet[columns[0]]; !allowed {
+		return nil, fmt.Errorf("field %q not allowed for sorting criteria", field)
+	}
+	return &plannedSort{column: columns[0]}, nil
+}
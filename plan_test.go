@@ -0,0 +1,263 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/velmie/qparser"
+)
+
+func newPlanTestBuilder(translations *int, options ...ResourceSelectBuilderOption) *ResourceSelectBuilder {
+	countTranslations := func(b *ResourceSelectBuilder) {
+		translate := b.translator
+		b.translator = func(fields []string) ([]string, error) {
+			*translations++
+			return translate(fields)
+		}
+	}
+	return newArticlesBuilder(append([]ResourceSelectBuilderOption{countTranslations, WithPlanCache(2)}, options...)...)
+}
+
+type planCacheTest struct {
+	query string
+	sql   string
+	args  []interface{}
+}
+
+var planCacheTests = []planCacheTest{
+	{
+		query: "filter[title]=eq:go&sort=-createdAt",
+		sql:   "SELECT id, title FROM articles WHERE title = ? ORDER BY created_at DESC",
+		args:  []interface{}{"go"},
+	},
+	{
+		query: "filter[title]=eq:rust&sort=-createdAt",
+		sql:   "SELECT id, title FROM articles WHERE title = ? ORDER BY created_at DESC",
+		args:  []interface{}{"rust"},
+	},
+	{
+		query: "fields[articles]=id&filter[id]=any:1,2,3",
+		sql:   "SELECT id FROM articles WHERE id IN (?,?,?)",
+		args:  []interface{}{"1", "2", "3"},
+	},
+	{
+		query: "fields[articles]=id&filter[id]=any:4,5,6",
+		sql:   "SELECT id FROM articles WHERE id IN (?,?,?)",
+		args:  []interface{}{"4", "5", "6"},
+	},
+}
+
+func TestPlanCache(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations)
+	ctx := context.Background()
+	for _, tt := range planCacheTests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb, err := builder.Build(ctx, query)
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		sql, args, err := sb.ToSQL()
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("query %q: expected %q %v, got %q %v", tt.query, tt.sql, tt.args, sql, args)
+		}
+	}
+	expected := PlanCacheStats{Hits: 2, Misses: 2, Size: 2}
+	if stats := builder.PlanCacheStats(); stats != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+	// two translations of each shape on the miss, none on the hit
+	if translations != 4 {
+		t.Errorf("expected 4 translations, got %d", translations)
+	}
+
+	query, err := qparser.ParseQuery("sort=createdAt")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = builder.Build(ctx, query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected = PlanCacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 2}
+	if stats := builder.PlanCacheStats(); stats != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+}
+
+func TestPlanCacheTemplate(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations,
+		AllowFiltering(
+			AllowedConditions{"title": {filterEq}, "id": {filterAny}, "createdAt": {"gt"}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithRelativeTimeFilter("createdAt", time.UTC),
+	)
+	ctx := ContextWithClock(context.Background(), func() time.Time { return relativeTimeNow })
+	tomorrow := time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query    string
+		sql      string
+		args     []interface{}
+		template bool
+	}{
+		{
+			query: "filter[title]=eq:go&filter[id]=any:1,2",
+			sql:   "SELECT id, title FROM articles WHERE title = ? AND id IN (?,?)",
+			args:  []interface{}{"go", "1", "2"},
+		},
+		{
+			query:    "filter[title]=eq:rust&filter[id]=any:3,4",
+			sql:      "SELECT id, title FROM articles WHERE title = ? AND id IN (?,?)",
+			args:     []interface{}{"rust", "3", "4"},
+			template: true,
+		},
+		{
+			query: "filter[id]=any:1,1",
+			sql:   "SELECT id, title FROM articles WHERE id IN (?,?)",
+			args:  []interface{}{"1", "1"},
+		},
+		{
+			// the order of the duplicate values is unknown, so the shape has no template
+			query: "filter[id]=any:2,3",
+			sql:   "SELECT id, title FROM articles WHERE id IN (?,?)",
+			args:  []interface{}{"2", "3"},
+		},
+		{
+			query: "filter[createdAt]=gt:2023-03-01",
+			sql:   "SELECT id, title FROM articles WHERE created_at > ?",
+			args:  []interface{}{time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			// the time bucket changes the condition, so it is not bound to the template
+			query: "filter[createdAt]=gt:today",
+			sql:   "SELECT id, title FROM articles WHERE created_at >= ?",
+			args:  []interface{}{tomorrow},
+		},
+	}
+	for _, tt := range tests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb, err := builder.Build(ctx, query)
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		_, template := sb.WhereParts[0].(*RawSQLWithArgs)
+		if template != tt.template {
+			t.Errorf("query %q: expected template %v, got where parts %+v", tt.query, tt.template, sb.WhereParts)
+		}
+		sql, args, err := sb.ToSQL()
+		if err != nil {
+			t.Fatalf("query %q: unexpected error %s", tt.query, err)
+		}
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("query %q: expected %q %v, got %q %v", tt.query, tt.sql, tt.args, sql, args)
+		}
+	}
+}
+
+func TestPlanCacheColumnsNotShared(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations)
+	ctx := context.Background()
+	query, err := qparser.ParseQuery("fields[articles]=id,title")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		sb, err := builder.Build(ctx, query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb.Columns[0].(Columns)[0] = "password"
+	}
+	sb, err := builder.Build(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, _, err := sb.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if expected := "SELECT id, title FROM articles"; sql != expected {
+		t.Errorf("expected %q, got %q", expected, sql)
+	}
+}
+
+func TestPlanCacheErrors(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations)
+	query, err := qparser.ParseQuery("filter[title]=any:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		var filterErr *FilterError
+		if _, err = builder.Build(context.Background(), query); !errors.As(err, &filterErr) {
+			t.Errorf("expected *FilterError, got %v", err)
+		}
+	}
+	if stats := builder.PlanCacheStats(); stats.Size != 0 || stats.Hits != 0 {
+		t.Errorf("failed plans must not be cached, got %+v", stats)
+	}
+}
+
+func TestPlanCachePolicy(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations, WithPolicy(func(ctx context.Context) (*Permissions, error) {
+		if ctx.Value(roleKey{}) == "admin" {
+			return nil, nil //nolint:nilnil // nil permissions mean that nothing is narrowed
+		}
+		return &Permissions{SelectFields: []string{"id"}, SortFields: []string{}}, nil
+	}))
+	query, err := qparser.ParseQuery("filter[title]=eq:go&sort=createdAt")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb, err := builder.Build(context.WithValue(context.Background(), roleKey{}, "admin"), query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, _, _ := sb.ToSQL()
+	if sql != "SELECT id, title FROM articles WHERE title = ? ORDER BY created_at ASC" {
+		t.Errorf("unexpected sql %q", sql)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err = builder.Build(context.Background(), query); !errors.As(err, &forbiddenErr) {
+		t.Errorf("expected *ForbiddenError, got %v", err)
+	}
+	if stats := builder.PlanCacheStats(); stats.Hits != 1 {
+		t.Errorf("expected the cached plan to be used, got %+v", stats)
+	}
+}
+
+func TestPlanCacheBypassedByTrace(t *testing.T) {
+	var translations int
+	builder := newPlanTestBuilder(&translations)
+	query, err := qparser.ParseQuery("filter[title]=eq:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	ctx, trace := WithTrace(context.Background())
+	if _, err = builder.Build(ctx, query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if stats := builder.PlanCacheStats(); stats != (PlanCacheStats{}) {
+		t.Errorf("expected the cache to be bypassed, got %+v", stats)
+	}
+	if len(trace.Steps) == 0 {
+		t.Error("expected trace steps")
+	}
+}
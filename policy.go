@@ -0,0 +1,106 @@
+package q2sql
+
+import "context"
+
+// Permissions narrows capabilities of the ResourceSelectBuilder for the particular request.
+// Nil list (or map) means that the corresponding capability is not narrowed,
+// whereas an empty one forbids everything.
+// The lists use the same terms as the corresponding options:
+// SelectFields as AllowSelectFields, SortFields as AllowSortingByFields
+// and Conditions as AllowFiltering. Sort expressions and computed fields are listed
+// in SortFields by their names.
+//
+// The lists of the Permissions created by NewPermissions are kept as sets, so the checks do not scan them;
+// the policy should create the Permissions once (e.g. per role) and return them for every request
+type Permissions struct {
+	SelectFields []string
+	SortFields   []string
+	Conditions   AllowedConditions
+
+	selectSet     map[string]struct{}
+	sortSet       map[string]struct{}
+	conditionSets map[string]map[string]struct{}
+}
+
+// NewPermissions creates Permissions with the precomputed sets of the lists,
+// the lists must not be changed afterwards. Nil list still means that the capability is not narrowed
+func NewPermissions(selectFields, sortFields []string, conditions AllowedConditions) *Permissions {
+	p := &Permissions{SelectFields: selectFields, SortFields: sortFields, Conditions: conditions}
+	if selectFields != nil {
+		p.selectSet = make(map[string]struct{}, len(selectFields))
+		fillMapKeys(p.selectSet, selectFields)
+	}
+	if sortFields != nil {
+		p.sortSet = make(map[string]struct{}, len(sortFields))
+		fillMapKeys(p.sortSet, sortFields)
+	}
+	if conditions != nil {
+		p.conditionSets = make(map[string]map[string]struct{}, len(conditions))
+		for field, names := range conditions {
+			p.conditionSets[field] = make(map[string]struct{}, len(names))
+			fillMapKeys(p.conditionSets[field], names)
+		}
+	}
+	return p
+}
+
+// Policy resolves permissions for the request, e.g. by the role of the principal carried by the context.
+// Nil permissions mean that nothing is narrowed
+type Policy func(ctx context.Context) (*Permissions, error)
+
+func (p *Permissions) selectAllowed(field string) bool {
+	if p == nil || p.SelectFields == nil {
+		return true
+	}
+	return containsOrScan(p.selectSet, p.SelectFields, field)
+}
+
+func (p *Permissions) sortAllowed(field string) bool {
+	if p == nil || p.SortFields == nil {
+		return true
+	}
+	return containsOrScan(p.sortSet, p.SortFields, field)
+}
+
+func (p *Permissions) conditionAllowed(field, condition string) bool {
+	if p == nil || p.Conditions == nil {
+		return true
+	}
+	if p.conditionSets != nil {
+		_, ok := p.conditionSets[field][condition]
+		return ok
+	}
+	return containsString(p.Conditions[field], condition)
+}
+
+// filterSelect returns fields which are allowed for selection
+func (p *Permissions) filterSelect(fields []string) []string {
+	if p == nil || p.SelectFields == nil {
+		return fields
+	}
+	allowed := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if containsOrScan(p.selectSet, p.SelectFields, field) {
+			allowed = append(allowed, field)
+		}
+	}
+	return allowed
+}
+
+// containsOrScan looks up the precomputed set, the list is scanned if the set is not computed
+func containsOrScan(set map[string]struct{}, list []string, s string) bool {
+	if set != nil {
+		_, ok := set[s]
+		return ok
+	}
+	return containsString(list, s)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
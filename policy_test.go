@@ -0,0 +1,141 @@
+package q2sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+type roleKey struct{}
+
+type policyTest struct {
+	role      string
+	query     string
+	sql       string
+	forbidden bool
+	expectErr bool
+}
+
+var policyTests = []policyTest{
+	{role: "admin", query: "fields[users]=id,email", sql: "SELECT id, email FROM users"},
+	{role: "user", query: "fields[users]=id,name", sql: "SELECT id, name FROM users"},
+	{role: "user", query: "", sql: "SELECT id, name FROM users"},
+	{role: "admin", query: "", sql: "SELECT id, name, email FROM users"},
+	{role: "user", query: "fields[users]=id,email", forbidden: true},
+	{role: "user", query: "fields[users]=id,unknown", expectErr: true},
+	{role: "admin", query: "filter[internalNotes]=eq:x", sql: "SELECT id, name, email FROM users WHERE internal_notes = ?"},
+	{role: "user", query: "filter[internalNotes]=eq:x", forbidden: true},
+	{role: "user", query: "filter[name]=eq:x", sql: "SELECT id, name FROM users WHERE name = ?"},
+	{role: "user", query: "filter[name]=neq:x", forbidden: true},
+	{role: "user", query: "filter[id]=eq:1", expectErr: true},
+	{role: "admin", query: "sort=email", sql: "SELECT id, name, email FROM users ORDER BY email ASC"},
+	{role: "user", query: "sort=email", forbidden: true},
+	{role: "user", query: "sort=id", expectErr: true},
+	{role: "admin", query: "sort=-relevance", sql: "SELECT id, name, email FROM users ORDER BY score DESC"},
+	{role: "user", query: "sort=-relevance", forbidden: true},
+	{role: "", query: "", expectErr: true},
+}
+
+func TestPolicy(t *testing.T) {
+	policy := func(ctx context.Context) (*Permissions, error) {
+		switch ctx.Value(roleKey{}) {
+		case "admin":
+			return nil, nil //nolint:nilnil // nil permissions mean that nothing is narrowed
+		case "user":
+			return &Permissions{
+				SelectFields: []string{"id", "name"},
+				SortFields:   []string{"name"},
+				Conditions:   AllowedConditions{"name": []string{filterEq}},
+			}, nil
+		}
+		return nil, errors.New("unknown role")
+	}
+	builder := NewResourceSelectBuilder(
+		"users",
+		MapTranslator(map[string]string{
+			"id":            "id",
+			"name":          "name",
+			"email":         "email",
+			"internalNotes": "internal_notes",
+		}),
+		WithDefaultFields([]string{"id", "name", "email"}),
+		AllowFiltering(
+			AllowedConditions{
+				"name":          []string{filterEq, "neq"},
+				"internalNotes": []string{filterEq},
+			},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		AllowSortingByFields([]string{"name", "email"}),
+		AllowSortingByExpressions(map[string]SortExpression{
+			"relevance": func(context.Context, *qparser.Query) (Sqlizer, error) {
+				return RawSQL("score"), nil
+			},
+		}),
+		WithPolicy(policy),
+	)
+	for i, tt := range policyTests {
+		meta := fmt.Sprintf("test %d (%s %q)", i, tt.role, tt.query)
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", meta, err)
+		}
+		ctx := context.WithValue(context.Background(), roleKey{}, tt.role)
+		sb, err := builder.Build(ctx, query)
+		var forbidden *ForbiddenError
+		if tt.forbidden != errors.As(err, &forbidden) {
+			t.Errorf("%s: unexpected forbidden status, got error %v", meta, err)
+			continue
+		}
+		if tt.forbidden {
+			continue
+		}
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: unexpected error status, got %v", meta, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		sql, _, err := sb.ToSQL()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", meta, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("%s: expected sql %q, got %q", meta, tt.sql, sql)
+		}
+	}
+}
+
+func TestNewPermissions(t *testing.T) {
+	literal := &Permissions{
+		SelectFields: []string{"id", "name"},
+		Conditions:   AllowedConditions{"name": []string{filterEq}},
+	}
+	compiled := NewPermissions(literal.SelectFields, literal.SortFields, literal.Conditions)
+	for _, field := range []string{"id", "name", "email", ""} {
+		if compiled.selectAllowed(field) != literal.selectAllowed(field) {
+			t.Errorf("select %q: expected %v", field, literal.selectAllowed(field))
+		}
+		if compiled.sortAllowed(field) != literal.sortAllowed(field) {
+			t.Errorf("sort %q: expected %v", field, literal.sortAllowed(field))
+		}
+		for _, condition := range []string{filterEq, "neq"} {
+			if compiled.conditionAllowed(field, condition) != literal.conditionAllowed(field, condition) {
+				t.Errorf("condition %q of %q: expected %v", condition, field, literal.conditionAllowed(field, condition))
+			}
+		}
+	}
+	fields := []string{"id", "email", "name"}
+	if got, want := compiled.filterSelect(fields), literal.filterSelect(fields); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if !NewPermissions(nil, []string{}, nil).selectAllowed("email") || NewPermissions(nil, []string{}, nil).sortAllowed("id") {
+		t.Error("nil list must not narrow, empty one must forbid everything")
+	}
+}
@@ -0,0 +1,28 @@
+package q2sql
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize limits the size of the buffers returned to the pool,
+// so a single huge query does not keep the memory forever
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -7,11 +7,14 @@ import (
 	"strings"
 )
 
+var errNoColumns = errors.New("select must have at least one column")
+
 // this builder is simplified version of the select
 // builder from the github.com/Masterminds/squirrel project
 // thanks to the project authors
 
 type SelectBuilder struct {
+	CTEs         []CTE
 	IsDistinct   bool
 	Columns      []Sqlizer
 	FromPart     Sqlizer
@@ -22,6 +25,23 @@ type SelectBuilder struct {
 	OrderByParts []Sqlizer
 	LimitPart    string
 	OffsetPart   string
+	LockPart     *Lock
+	// Dialect is used in order to validate dialect specific clauses
+	Dialect Dialect
+	// Page is set by pagination extensions, it is not rendered to SQL
+	Page *PageInfo
+}
+
+// With adds the common table expression: WITH name AS (query)
+func (s *SelectBuilder) With(name string, query Sqlizer) *SelectBuilder {
+	s.CTEs = append(s.CTEs, CTE{Name: name, Query: query})
+	return s
+}
+
+// WithRecursive adds the recursive common table expression: WITH RECURSIVE name AS (query)
+func (s *SelectBuilder) WithRecursive(name string, query Sqlizer) *SelectBuilder {
+	s.CTEs = append(s.CTEs, CTE{Name: name, Query: query, Recursive: true})
+	return s
 }
 
 func (s *SelectBuilder) Select(columns []string) *SelectBuilder {
@@ -32,11 +52,29 @@ func (s *SelectBuilder) Select(columns []string) *SelectBuilder {
 	return s
 }
 
+// Lock adds the row locking clause, e.g. Lock(Lock{Strength: ForUpdate, Wait: SkipLocked})
+func (s *SelectBuilder) Lock(lock Lock) *SelectBuilder {
+	s.LockPart = &lock
+	return s
+}
+
+// Column adds the expression to the select list, e.g. &Alias{Expr: RowNumber, Name: "rn"}
+func (s *SelectBuilder) Column(column Sqlizer) *SelectBuilder {
+	s.Columns = append(s.Columns, column)
+	return s
+}
+
 func (s *SelectBuilder) From(from string) *SelectBuilder {
 	s.FromPart = RawSQL(from)
 	return s
 }
 
+// FromSelect sets the subquery as the source: FROM (SELECT ...) AS alias
+func (s *SelectBuilder) FromSelect(from Sqlizer, alias string) *SelectBuilder {
+	s.FromPart = &Alias{Expr: &SubQuery{Query: from}, Name: alias}
+	return s
+}
+
 func (s *SelectBuilder) Join(clause Sqlizer) *SelectBuilder {
 	s.Joins = append(s.Joins, clause)
 	return s
@@ -77,12 +115,32 @@ func (s *SelectBuilder) Distinct() *SelectBuilder {
 	return s
 }
 
-func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
-	sql := new(bytes.Buffer)
-	args = make([]interface{}, 0)
+func (s *SelectBuilder) ToSQL() (string, []interface{}, error) {
+	args := make([]interface{}, 0)
 	if len(s.Columns) == 0 {
-		err = errors.New("select must have at least one column")
-		return
+		return "", args, errNoColumns
+	}
+	sql := getBuffer()
+	defer putBuffer(sql)
+	args, err := s.WriteSQL(sql, args)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql.String(), args, nil
+}
+
+// WriteSQL writes the query into the buffer, see SQLWriter
+func (s *SelectBuilder) WriteSQL(sql *bytes.Buffer, args []interface{}) ([]interface{}, error) {
+	if len(s.Columns) == 0 {
+		return nil, errNoColumns
+	}
+
+	var err error
+	if len(s.CTEs) > 0 {
+		args, err = writeCTEs(s.CTEs, sql, args)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	sql.WriteString("SELECT ")
@@ -92,14 +150,14 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 
 	args, err = appendToSQL(s.Columns, sql, ",", args)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	if s.FromPart != nil {
 		sql.WriteString(" FROM ")
-		args, err = appendToSQL([]Sqlizer{s.FromPart}, sql, "", args)
+		args, err = WriteSQL(sql, args, s.FromPart)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
 
@@ -107,7 +165,7 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 		sql.WriteString(" ")
 		args, err = appendToSQL(s.Joins, sql, " ", args)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
 
@@ -115,7 +173,7 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 		sql.WriteString(" WHERE ")
 		args, err = appendToSQL(s.WhereParts, sql, " AND ", args)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
 
@@ -128,7 +186,7 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 		sql.WriteString(" HAVING ")
 		args, err = appendToSQL(s.HavingParts, sql, " AND ", args)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
 
@@ -136,7 +194,7 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 		sql.WriteString(" ORDER BY ")
 		args, err = appendToSQL(s.OrderByParts, sql, ", ", args)
 		if err != nil {
-			return
+			return nil, err
 		}
 	}
 
@@ -149,7 +207,15 @@ func (s *SelectBuilder) ToSQL() (sqlStr string, args []interface{}, err error) {
 		sql.WriteString(" OFFSET ")
 		sql.WriteString(s.OffsetPart)
 	}
-	sqlStr = sql.String()
 
-	return sqlStr, args, err
+	if s.LockPart != nil {
+		var lock string
+		lock, err = s.LockPart.toSQL(s.Dialect)
+		if err != nil {
+			return nil, err
+		}
+		sql.WriteString(" ")
+		sql.WriteString(lock)
+	}
+	return args, nil
 }
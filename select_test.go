@@ -61,6 +61,31 @@ var selectBuilderTests = []selectBuilderTest{
 		args:  []interface{}{1},
 		err:   false,
 	},
+	{
+		b: new(SelectBuilder).
+			Select([]string{"t.author", "t.total"}).
+			FromSelect(
+				new(SelectBuilder).
+					Select([]string{"author", "COUNT(*) AS total"}).
+					From("articles").
+					Where(&Eq{"status", "published"}).
+					GroupBy("author"),
+				"t",
+			).
+			Where(&Gt{"t.total", 10}),
+		query: "SELECT t.author, t.total FROM (SELECT author, COUNT(*) AS total FROM articles WHERE status = ? GROUP BY author) AS t WHERE t.total > ?",
+		args:  []interface{}{"published", 10},
+		err:   false,
+	},
+	{
+		b: new(SelectBuilder).
+			Select([]string{"id", "title"}).
+			Column(&Alias{Expr: &RawSQLWithArgs{SQL: "likes * ?", Args: []interface{}{2}}, Name: "score"}).
+			From("articles"),
+		query: "SELECT id, title,likes * ? AS score FROM articles",
+		args:  []interface{}{2},
+		err:   false,
+	},
 }
 
 func TestSelectBuilder(t *testing.T) {
@@ -0,0 +1,36 @@
+package q2sql
+
+import "bytes"
+
+// SQLWriter is implemented by the expressions which are able to write SQL into the shared buffer.
+// Unlike ToSQL it does not create a new string and a new slice of arguments for each
+// nested expression, so deep expression trees are rendered without copying
+type SQLWriter interface {
+	// WriteSQL writes SQL into the buffer and returns args with the expression arguments appended
+	WriteSQL(buf *bytes.Buffer, args []interface{}) ([]interface{}, error)
+}
+
+// WriteSQL writes the expression into the buffer and appends its arguments to args.
+// The expressions which implement only Sqlizer are rendered by means of ToSQL
+func WriteSQL(buf *bytes.Buffer, args []interface{}, s Sqlizer) ([]interface{}, error) {
+	if w, ok := s.(SQLWriter); ok {
+		return w.WriteSQL(buf, args)
+	}
+	sql, sqlArgs, err := s.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(sql)
+	return append(args, sqlArgs...), nil
+}
+
+// writerToSQL implements ToSQL by means of WriteSQL
+func writerToSQL(w SQLWriter) (string, []interface{}, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	args, err := w.WriteSQL(buf, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return buf.String(), args, nil
+}
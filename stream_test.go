@@ -0,0 +1,110 @@
+package q2sql
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// plainSqlizer implements only Sqlizer
+type plainSqlizer struct {
+	sql  string
+	args []interface{}
+}
+
+func (p plainSqlizer) ToSQL() (string, []interface{}, error) {
+	return p.sql, p.args, nil
+}
+
+func deepExpression(depth int) Sqlizer {
+	var expr Sqlizer = &Eq{Field: "f0", Value: 0}
+	for i := 1; i < depth; i++ {
+		expr = And{expr, Or{&Eq{Field: "f", Value: i}, IsNull("f")}}
+	}
+	return expr
+}
+
+func TestWriteSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Sqlizer
+		out  string
+		args []interface{}
+	}{
+		{
+			name: "user Sqlizer is adapted",
+			in:   And{&Eq{Field: "a", Value: 1}, plainSqlizer{sql: "b = ?", args: []interface{}{2}}},
+			out:  "(a = ? AND b = ?)",
+			args: []interface{}{1, 2},
+		},
+		{
+			name: "user Sqlizer nested in the sub query",
+			in: &In{Field: "id", Values: []interface{}{
+				new(SelectBuilder).Select([]string{"id"}).From("t").Where(plainSqlizer{sql: "x > ?", args: []interface{}{3}}),
+			}},
+			out:  "id IN (SELECT id FROM t WHERE x > ?)",
+			args: []interface{}{3},
+		},
+		{
+			name: "empty parts are skipped without separators",
+			in:   And{RawSQL(""), &Eq{Field: "a", Value: 1}, plainSqlizer{}, RawSQL("b")},
+			out:  "(a = ? AND b)",
+			args: []interface{}{1},
+		},
+		{
+			name: "args of empty parts are dropped",
+			in:   And{&RawSQLWithArgs{SQL: "", Args: []interface{}{1}}, &Eq{Field: "a", Value: 2}, plainSqlizer{args: []interface{}{3}}},
+			out:  "(a = ?)",
+			args: []interface{}{2},
+		},
+		{
+			name: "deep tree",
+			in:   deepExpression(3),
+			out:  "((f0 = ? AND (f = ? OR f IS NULL)) AND (f = ? OR f IS NULL))",
+			args: []interface{}{0, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			buf.WriteString("WHERE ")
+			args, err := WriteSQL(buf, []interface{}{"prefix"}, tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if buf.String() != "WHERE "+tt.out {
+				t.Errorf("expected %q, got %q", "WHERE "+tt.out, buf.String())
+			}
+			want := append([]interface{}{"prefix"}, tt.args...)
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("expected args %+v, got %+v", want, args)
+			}
+			sql, _, err := tt.in.ToSQL()
+			if err != nil {
+				t.Fatalf("unexpected ToSQL error: %s", err)
+			}
+			if sql != tt.out {
+				t.Errorf("expected ToSQL to return %q, got %q", tt.out, sql)
+			}
+		})
+	}
+}
+
+func TestWriteSQLError(t *testing.T) {
+	_, err := WriteSQL(&bytes.Buffer{}, nil, And{&Eq{Field: "a", Value: 1}, &In{Field: "b"}})
+	if err == nil {
+		t.Error("expected error for empty IN values")
+	}
+}
+
+func BenchmarkDeepExpressionToSQL(b *testing.B) {
+	expr := deepExpression(50)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := expr.ToSQL(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
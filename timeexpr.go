@@ -0,0 +1,450 @@
+package q2sql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock returns the current time
+type Clock func() time.Time
+
+type clockKey struct{}
+
+// ContextWithClock returns a copy of the given context which carries the clock.
+// The clock is used in order to resolve relative time expressions such as "now-7d",
+// so it is possible to get deterministic results e.g. in tests
+func ContextWithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ClockFromContext returns the clock carried by the context,
+// time.Now is returned in case if the context has no clock
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok && clock != nil {
+		return clock
+	}
+	return time.Now
+}
+
+// ArgsResolver converts filter arguments taken from the query string
+// to the values which are passed to the Condition
+type ArgsResolver func(ctx context.Context, args []string) ([]interface{}, error)
+
+// RelativeTimeArgs creates ArgsResolver which converts each argument to time.Time
+// by means of the ParseRelativeTime function, the calendar keywords are converted to TimeBucket.
+// The current time is taken from the clock carried by the context (see ContextWithClock).
+// The loc is used to calculate calendar based values such as "today" or "startOfMonth",
+// if loc is nil then the location of the clock's time is used
+func RelativeTimeArgs(loc *time.Location) ArgsResolver {
+	return func(ctx context.Context, args []string) ([]interface{}, error) {
+		now := ClockFromContext(ctx)()
+		if loc != nil {
+			now = now.In(loc)
+		}
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			t, bucket, err := parseRelativeTime(arg, now)
+			if err != nil {
+				return nil, err
+			}
+			if bucket != nil {
+				values[i] = *bucket
+				continue
+			}
+			values[i] = t
+		}
+		return values, nil
+	}
+}
+
+const daysInWeek = 7
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// ParseRelativeTime parses the time expression relative to the given "now".
+//
+// The expression consists of the base followed by any number of signed offsets.
+// The base is either an absolute time (RFC 3339, "2006-01-02T15:04:05" or "2006-01-02")
+// or one of the keywords:
+//
+//	now, today, yesterday, tomorrow,
+//	startOfHour, startOfDay, startOfWeek, startOfMonth, startOfYear
+//
+// The start of the week is Monday.
+// An offset is either the ISO-8601 duration such as "P1M2DT3H" or the short form
+// which is a sequence of numbers with units: "y" (years), "mo" (months), "w" (weeks),
+// "d" (days), "h" (hours), "m" (minutes), "s" (seconds), "ms" (milliseconds).
+// Calendar units (years, months, weeks, days) are applied by means of time.AddDate.
+//
+// for example:
+//
+//	"now-7d", "today+1d", "startOfMonth-P1M", "2023-01-01+12h30m"
+//
+// Note that unescaped "+" in a query string is decoded as a space,
+// so spaces are treated as "+" signs
+func ParseRelativeTime(expr string, now time.Time) (time.Time, error) {
+	t, _, err := parseRelativeTime(expr, now)
+	return t, err
+}
+
+// TimeBucket is the period [Start, End) denoted by the calendar keyword, e.g. "today" is the current day,
+// "startOfMonth-1mo" is the previous month. The builder passes Start to the condition,
+// except for the equality which is expanded into the range "field >= Start AND field < End"
+// (and its negation for the inequality), so "eq:today" matches the whole day
+type TimeBucket struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ParseTimeBucket parses the expression the same way as ParseRelativeTime,
+// the second return value is false if the base of the expression is not a calendar keyword
+func ParseTimeBucket(expr string, now time.Time) (TimeBucket, bool, error) {
+	t, bucket, err := parseRelativeTime(expr, now)
+	if err != nil || bucket == nil {
+		return TimeBucket{Start: t, End: t}, false, err
+	}
+	return *bucket, true, nil
+}
+
+// parseRelativeTime parses the expression, the bucket is returned if the base is a calendar keyword
+func parseRelativeTime(expr string, now time.Time) (time.Time, *TimeBucket, error) {
+	if expr == "" {
+		return time.Time{}, nil, fmt.Errorf("time expression is empty")
+	}
+	expr = strings.ReplaceAll(expr, " ", "+")
+	if t, ok := parseAbsoluteTime(expr, now.Location()); ok {
+		return t, nil, nil
+	}
+	base, rest := splitTimeBase(expr)
+	t, err := resolveTimeBase(base, now)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	for rest != "" {
+		sign := 1
+		switch rest[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return time.Time{}, nil, fmt.Errorf("unexpected %q in the time expression %q", rest, expr)
+		}
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "+-")
+		if end == -1 {
+			end = len(rest)
+		}
+		var offset timeOffset
+		offset, err = parseTimeOffset(rest[:end])
+		if err != nil {
+			return time.Time{}, nil, fmt.Errorf("invalid offset in the time expression %q: %w", expr, err)
+		}
+		t = offset.apply(t, sign)
+		rest = rest[end:]
+	}
+	size, ok := bucketSizes[base]
+	if !ok {
+		return t, nil, nil
+	}
+	return t, &TimeBucket{Start: t, End: size.apply(t, 1)}, nil
+}
+
+// bucketSizes are the periods denoted by the calendar keywords
+var bucketSizes = map[string]timeOffset{
+	"today":        {days: 1},
+	"yesterday":    {days: 1},
+	"tomorrow":     {days: 1},
+	"startOfDay":   {days: 1},
+	"startOfHour":  {duration: time.Hour},
+	"startOfWeek":  {days: daysInWeek},
+	"startOfMonth": {months: 1},
+	"startOfYear":  {years: 1},
+}
+
+// parseAbsoluteTime parses the time in one of the timeLayouts,
+// the time without the zone is considered to be in the given location
+func parseAbsoluteTime(s string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// splitTimeBase splits expression into the base and the list of offsets.
+// Absolute base is the longest prefix which is a valid absolute time,
+// it allows using dates with offsets like "2023-01-01-P1D"
+func splitTimeBase(expr string) (base, rest string) {
+	i := strings.IndexAny(expr, "+-")
+	if i == -1 {
+		return expr, ""
+	}
+	if i > 0 && isTimeKeyword(expr[:i]) {
+		return expr[:i], expr[i:]
+	}
+	for j := len(expr) - 1; j > 0; j-- {
+		if expr[j] != '+' && expr[j] != '-' {
+			continue
+		}
+		if !isAbsoluteTime(expr[:j]) {
+			continue
+		}
+		return expr[:j], expr[j:]
+	}
+	return expr, ""
+}
+
+func isTimeKeyword(s string) bool {
+	_, err := resolveTimeBase(s, time.Time{})
+	return err == nil && !isAbsoluteTime(s)
+}
+
+func isAbsoluteTime(s string) bool {
+	_, ok := parseAbsoluteTime(s, time.UTC)
+	return ok
+}
+
+func resolveTimeBase(base string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	loc := now.Location()
+	switch base {
+	case "now":
+		return now, nil
+	case "today", "startOfDay":
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), nil
+	case "yesterday":
+		return time.Date(y, m, d-1, 0, 0, 0, 0, loc), nil
+	case "tomorrow":
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc), nil
+	case "startOfHour":
+		return time.Date(y, m, d, now.Hour(), 0, 0, 0, loc), nil
+	case "startOfWeek":
+		shift := (int(now.Weekday()) + daysInWeek - int(time.Monday)) % daysInWeek
+		return time.Date(y, m, d-shift, 0, 0, 0, 0, loc), nil
+	case "startOfMonth":
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc), nil
+	case "startOfYear":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, loc), nil
+	}
+	if t, ok := parseAbsoluteTime(base, loc); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown time expression %q", base)
+}
+
+type timeOffset struct {
+	years    int
+	months   int
+	days     int
+	duration time.Duration
+}
+
+func (o timeOffset) apply(t time.Time, sign int) time.Time {
+	return t.AddDate(sign*o.years, sign*o.months, sign*o.days).Add(time.Duration(sign) * o.duration)
+}
+
+func parseTimeOffset(s string) (timeOffset, error) {
+	if s == "" {
+		return timeOffset{}, fmt.Errorf("offset is empty")
+	}
+	if s[0] == 'P' {
+		return parseISODuration(s)
+	}
+	var offset timeOffset
+	for s != "" {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return timeOffset{}, fmt.Errorf("number is expected in %q", s)
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return timeOffset{}, err
+		}
+		s = s[i:]
+		j := 0
+		for j < len(s) && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		if err = offset.add(n, s[:j]); err != nil {
+			return timeOffset{}, err
+		}
+		s = s[j:]
+	}
+	return offset, nil
+}
+
+func (o *timeOffset) add(n int, unit string) error {
+	switch unit {
+	case "y":
+		o.years += n
+	case "mo":
+		o.months += n
+	case "w":
+		o.days += n * daysInWeek
+	case "d":
+		o.days += n
+	case "h":
+		o.duration += time.Duration(n) * time.Hour
+	case "m":
+		o.duration += time.Duration(n) * time.Minute
+	case "s":
+		o.duration += time.Duration(n) * time.Second
+	case "ms":
+		o.duration += time.Duration(n) * time.Millisecond
+	default:
+		return fmt.Errorf("unknown time unit %q", unit)
+	}
+	return nil
+}
+
+// parseISODuration parses ISO-8601 duration such as "P1Y2M3W4DT5H6M7.5S"
+func parseISODuration(s string) (timeOffset, error) {
+	var (
+		offset   timeOffset
+		timePart bool
+		parsed   bool
+	)
+	rest := s[1:]
+	for rest != "" {
+		if rest[0] == 'T' {
+			if timePart {
+				return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+			}
+			timePart = true
+			rest = rest[1:]
+			continue
+		}
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		num, designator := rest[:i], rest[i]
+		rest = rest[i+1:]
+		parsed = true
+		if timePart {
+			f, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+			}
+			var unit time.Duration
+			switch designator {
+			case 'H':
+				unit = time.Hour
+			case 'M':
+				unit = time.Minute
+			case 'S':
+				unit = time.Second
+			default:
+				return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+			}
+			offset.duration += time.Duration(f * float64(unit))
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		switch designator {
+		case 'Y':
+			offset.years += n
+		case 'M':
+			offset.months += n
+		case 'W':
+			offset.days += n * daysInWeek
+		case 'D':
+			offset.days += n
+		default:
+			return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+	}
+	if !parsed {
+		return timeOffset{}, fmt.Errorf("invalid ISO-8601 duration %q", s)
+	}
+	return offset, nil
+}
+
+// unwrapTimeBuckets replaces the buckets by their start,
+// the returned slice contains the bucket by the index of the value or nil if there are no buckets
+func unwrapTimeBuckets(values []interface{}) []*TimeBucket {
+	var buckets []*TimeBucket
+	for i, value := range values {
+		bucket, ok := value.(TimeBucket)
+		if !ok {
+			continue
+		}
+		if buckets == nil {
+			buckets = make([]*TimeBucket, len(values))
+		}
+		buckets[i] = &bucket
+		values[i] = bucket.Start
+	}
+	return buckets
+}
+
+// expandTimeBuckets replaces the equality to the bucket start by the range of the bucket,
+// "greater than" and "less than or equal to" the bucket are compared with its end
+func expandTimeBuckets(cond Sqlizer, buckets []*TimeBucket) Sqlizer {
+	switch c := cond.(type) {
+	case *Gt:
+		if buckets[0] != nil {
+			return &Ge{Field: c.Field, Value: buckets[0].End}
+		}
+	case *Le:
+		if buckets[0] != nil {
+			return &Lt{Field: c.Field, Value: buckets[0].End}
+		}
+	case *Eq:
+		if buckets[0] != nil {
+			return buckets[0].within(c.Field)
+		}
+	case *Neq:
+		if buckets[0] != nil {
+			return buckets[0].outside(c.Field)
+		}
+	case *In:
+		if len(c.Values) == len(buckets) {
+			or := make(Or, len(c.Values))
+			for i, value := range c.Values {
+				or[i] = &Eq{Field: c.Field, Value: value}
+				if buckets[i] != nil {
+					or[i] = buckets[i].within(c.Field)
+				}
+			}
+			return or
+		}
+	case *NotIn:
+		if len(c.Values) == len(buckets) {
+			and := make(And, len(c.Values))
+			for i, value := range c.Values {
+				and[i] = &Neq{Field: c.Field, Value: value}
+				if buckets[i] != nil {
+					and[i] = buckets[i].outside(c.Field)
+				}
+			}
+			return and
+		}
+	}
+	return cond
+}
+
+func (b *TimeBucket) within(field string) Sqlizer {
+	return And{&Ge{Field: field, Value: b.Start}, &Lt{Field: field, Value: b.End}}
+}
+
+func (b *TimeBucket) outside(field string) Sqlizer {
+	return Or{&Lt{Field: field, Value: b.Start}, &Ge{Field: field, Value: b.End}}
+}
@@ -0,0 +1,281 @@
+package q2sql
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/velmie/qparser"
+)
+
+// Wednesday
+var relativeTimeNow = time.Date(2023, time.March, 15, 10, 30, 45, 0, time.UTC)
+
+type relativeTimeTest struct {
+	expr      string
+	expected  time.Time
+	expectErr bool
+}
+
+var relativeTimeTests = []relativeTimeTest{
+	{expr: "now", expected: relativeTimeNow},
+	{expr: "today", expected: time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)},
+	{expr: "yesterday", expected: time.Date(2023, time.March, 14, 0, 0, 0, 0, time.UTC)},
+	{expr: "tomorrow", expected: time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+	{expr: "startOfHour", expected: time.Date(2023, time.March, 15, 10, 0, 0, 0, time.UTC)},
+	{expr: "startOfWeek", expected: time.Date(2023, time.March, 13, 0, 0, 0, 0, time.UTC)},
+	{expr: "startOfMonth", expected: time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	{expr: "startOfYear", expected: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	{expr: "now-7d", expected: time.Date(2023, time.March, 8, 10, 30, 45, 0, time.UTC)},
+	{expr: "now+1h30m", expected: time.Date(2023, time.March, 15, 12, 0, 45, 0, time.UTC)},
+	{expr: "now 1h30m", expected: time.Date(2023, time.March, 15, 12, 0, 45, 0, time.UTC)},
+	{expr: "today-1w+2d", expected: time.Date(2023, time.March, 10, 0, 0, 0, 0, time.UTC)},
+	{expr: "startOfMonth-1mo", expected: time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	{expr: "startOfMonth-P1M", expected: time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	{expr: "now-P1Y2M3DT4H5M6S", expected: time.Date(2022, time.January, 12, 6, 25, 39, 0, time.UTC)},
+	{expr: "now+PT0.5S", expected: relativeTimeNow.Add(500 * time.Millisecond)},
+	{expr: "2023-01-01", expected: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	{expr: "2023-01-01-P1D", expected: time.Date(2022, time.December, 31, 0, 0, 0, 0, time.UTC)},
+	{expr: "2023-01-01T10:00:00+02:00+1h", expected: time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)},
+	{expr: "", expectErr: true},
+	{expr: "someday", expectErr: true},
+	{expr: "now-7", expectErr: true},
+	{expr: "now-7x", expectErr: true},
+	{expr: "now-P", expectErr: true},
+	{expr: "now-P1H", expectErr: true},
+	{expr: "now*2d", expectErr: true},
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	for _, tt := range relativeTimeTests {
+		got, err := ParseRelativeTime(tt.expr, relativeTimeNow)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("ParseRelativeTime(%q) unexpected error status: got %v, want %v", tt.expr, err, tt.expectErr)
+			continue
+		}
+		if tt.expectErr {
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("ParseRelativeTime(%q):\n\tgot  %s\n\twant %s", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestRelativeTimeArgs(t *testing.T) {
+	ctx := ContextWithClock(context.Background(), func() time.Time { return relativeTimeNow })
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	values, err := RelativeTimeArgs(loc)(ctx, []string{"today", "now-1d", "2023-03-01"})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := []interface{}{
+		TimeBucket{
+			Start: time.Date(2023, time.March, 15, 0, 0, 0, 0, loc),
+			End:   time.Date(2023, time.March, 16, 0, 0, 0, 0, loc),
+		},
+		relativeTimeNow.Add(-24 * time.Hour).In(loc),
+		time.Date(2023, time.March, 1, 0, 0, 0, 0, loc),
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected values:\n\tgot  %+v\n\twant %+v", values, expected)
+	}
+	if _, err = RelativeTimeArgs(nil)(ctx, []string{"never"}); err == nil {
+		t.Error("expected error for the invalid expression")
+	}
+}
+
+func TestClockFromContext(t *testing.T) {
+	before := time.Now()
+	now := ClockFromContext(context.Background())()
+	if now.Before(before) {
+		t.Errorf("expected the default clock to return the current time, got %s", now)
+	}
+}
+
+func TestResourceSelectBuilderWithFilterArgsResolver(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		resourceName,
+		MapTranslator(map[string]string{"createdAt": resourceFieldCreatedAt}),
+		WithDefaultFields([]string{"*"}),
+		AllowFiltering(
+			AllowedConditions{"createdAt": []string{"ge"}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithFilterArgsResolver("createdAt", RelativeTimeArgs(time.UTC)),
+	)
+	ctx := ContextWithClock(context.Background(), func() time.Time { return relativeTimeNow })
+
+	query, err := qparser.ParseQuery("filter[createdAt]=ge:now-7d")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sb, err := builder.Build(ctx, query)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	sql, args, err := sb.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	const expectedSQL = "SELECT * FROM articles WHERE created_at >= ?"
+	if sql != expectedSQL {
+		t.Errorf("expected sql %q, got %q", expectedSQL, sql)
+	}
+	expectedArgs := []interface{}{time.Date(2023, time.March, 8, 10, 30, 45, 0, time.UTC)}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected args %+v, got %+v", expectedArgs, args)
+	}
+
+	query, err = qparser.ParseQuery("filter[createdAt]=ge:someday")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	_, err = builder.Build(ctx, query)
+	if _, ok := err.(*FilterError); !ok {
+		t.Errorf("expected *FilterError, got %T %v", err, err)
+	}
+}
+
+func TestParseRelativeTimeInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	now := relativeTimeNow.In(loc)
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{expr: "2023-01-01", expected: time.Date(2023, time.January, 1, 0, 0, 0, 0, loc)},
+		{expr: "2023-01-01T10:00:00+1h", expected: time.Date(2023, time.January, 1, 11, 0, 0, 0, loc)},
+		{expr: "2023-01-01T10:00:00Z", expected: time.Date(2023, time.January, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseRelativeTime(tt.expr, now)
+		if err != nil {
+			t.Errorf("ParseRelativeTime(%q) unexpected error %s", tt.expr, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("ParseRelativeTime(%q):\n\tgot  %s\n\twant %s", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestParseTimeBucket(t *testing.T) {
+	tests := []struct {
+		expr   string
+		bucket bool
+		start  time.Time
+		end    time.Time
+	}{
+		{expr: "today", bucket: true, start: time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC), end: time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "startOfHour", bucket: true, start: time.Date(2023, time.March, 15, 10, 0, 0, 0, time.UTC), end: time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{expr: "startOfWeek", bucket: true, start: time.Date(2023, time.March, 13, 0, 0, 0, 0, time.UTC), end: time.Date(2023, time.March, 20, 0, 0, 0, 0, time.UTC)},
+		{expr: "startOfMonth-1mo", bucket: true, start: time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "startOfYear", bucket: true, start: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "now-1d", start: time.Date(2023, time.March, 14, 10, 30, 45, 0, time.UTC)},
+		{expr: "2023-01-01", start: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok, err := ParseTimeBucket(tt.expr, relativeTimeNow)
+		if err != nil {
+			t.Errorf("ParseTimeBucket(%q) unexpected error %s", tt.expr, err)
+			continue
+		}
+		if ok != tt.bucket {
+			t.Errorf("ParseTimeBucket(%q) expected bucket %v, got %v", tt.expr, tt.bucket, ok)
+			continue
+		}
+		if !got.Start.Equal(tt.start) || (tt.bucket && !got.End.Equal(tt.end)) {
+			t.Errorf("ParseTimeBucket(%q):\n\tgot  %s - %s\n\twant %s - %s", tt.expr, got.Start, got.End, tt.start, tt.end)
+		}
+	}
+}
+
+func TestTimeBucketConditions(t *testing.T) {
+	builder := NewResourceSelectBuilder(
+		resourceName,
+		MapTranslator(map[string]string{"createdAt": resourceFieldCreatedAt}),
+		WithDefaultFields([]string{"*"}),
+		AllowFiltering(
+			AllowedConditions{"createdAt": []string{filterEq, "neq", filterAny, "gt", "ge", "lt", "le"}},
+			testConditions(),
+			DefaultFilterExpressionParser,
+		),
+		WithFilterArgsResolver("createdAt", RelativeTimeArgs(time.UTC)),
+	)
+	ctx := ContextWithClock(context.Background(), func() time.Time { return relativeTimeNow })
+	today := time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+	yesterday := today.AddDate(0, 0, -1)
+	now := relativeTimeNow
+	tests := []struct {
+		query string
+		sql   string
+		args  []interface{}
+	}{
+		{
+			query: "filter[createdAt]=eq:today",
+			sql:   "SELECT * FROM articles WHERE (created_at >= ? AND created_at < ?)",
+			args:  []interface{}{today, tomorrow},
+		},
+		{
+			query: "filter[createdAt]=neq:today",
+			sql:   "SELECT * FROM articles WHERE (created_at < ? OR created_at >= ?)",
+			args:  []interface{}{today, tomorrow},
+		},
+		{
+			query: "filter[createdAt]=any:yesterday,now",
+			sql:   "SELECT * FROM articles WHERE ((created_at >= ? AND created_at < ?) OR created_at = ?)",
+			args:  []interface{}{yesterday, today, now},
+		},
+		{
+			query: "filter[createdAt]=gt:today",
+			sql:   "SELECT * FROM articles WHERE created_at >= ?",
+			args:  []interface{}{tomorrow},
+		},
+		{
+			query: "filter[createdAt]=ge:today",
+			sql:   "SELECT * FROM articles WHERE created_at >= ?",
+			args:  []interface{}{today},
+		},
+		{
+			query: "filter[createdAt]=lt:today",
+			sql:   "SELECT * FROM articles WHERE created_at < ?",
+			args:  []interface{}{today},
+		},
+		{
+			query: "filter[createdAt]=le:today",
+			sql:   "SELECT * FROM articles WHERE created_at < ?",
+			args:  []interface{}{tomorrow},
+		},
+		{
+			query: "filter[createdAt]=gt:now",
+			sql:   "SELECT * FROM articles WHERE created_at > ?",
+			args:  []interface{}{now},
+		},
+	}
+	for _, tt := range tests {
+		query, err := qparser.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		sb, err := builder.Build(ctx, query)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.query, err)
+			continue
+		}
+		sql, args, err := sb.ToSQL()
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.query, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("%s: expected sql %q, got %q", tt.query, tt.sql, sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %+v, got %+v", tt.query, tt.args, args)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package q2sql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// These are stages of the Build which are recorded to the Trace
+const (
+	TraceStagePolicy    = "policy"
+	TraceStageLimits    = "limits"
+	TraceStageAggregate = "aggregate"
+	TraceStageSelect    = "select"
+	TraceStageFilter    = "filter"
+	TraceStageSort      = "sort"
+	TraceStageExtension = "extension"
+)
+
+// TraceStep describes how a part of the query was processed
+type TraceStep struct {
+	Stage string `json:"stage"`
+	// Parameter is the query parameter, e.g. "filter[title]"
+	Parameter string `json:"parameter,omitempty"`
+	// Input is the value of the parameter as it came from the client
+	Input string `json:"input,omitempty"`
+	// Translation is the result of the translation of the field names
+	Translation []string `json:"translation,omitempty"`
+	// Check describes which checks are passed
+	Check string `json:"check,omitempty"`
+	// Condition is the name of the created condition
+	Condition string `json:"condition,omitempty"`
+	// Extension is the name of the function of the extension
+	Extension string        `json:"extension,omitempty"`
+	SQL       string        `json:"sql,omitempty"`
+	Args      []interface{} `json:"args,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Trace records how the query string became SQL, it is filled by the Build
+// if the trace is attached to the context by means of the WithTrace.
+// The trace could be marshaled to JSON e.g. in order to be returned by a debug endpoint
+type Trace struct {
+	mu    sync.Mutex
+	Steps []TraceStep   `json:"steps"`
+	SQL   string        `json:"sql,omitempty"`
+	Args  []interface{} `json:"args,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+type traceKey struct{}
+
+// WithTrace attaches a new trace to the context
+//
+//	ctx, trace := q2sql.WithTrace(ctx)
+//	sb, err := builder.Build(ctx, query)
+//	debug, _ := json.Marshal(trace)
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{Steps: make([]TraceStep, 0)}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+// TraceFromContext returns the trace attached to the context or nil
+func TraceFromContext(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// MarshalJSON marshals the trace safely while it could be filled concurrently
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	type trace struct {
+		Steps []TraceStep   `json:"steps"`
+		SQL   string        `json:"sql,omitempty"`
+		Args  []interface{} `json:"args,omitempty"`
+		Error string        `json:"error,omitempty"`
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return json.Marshal(trace{Steps: t.Steps, SQL: t.SQL, Args: t.Args, Error: t.Error})
+}
+
+// record adds the step, the expression is rendered to the step SQL.
+// It does nothing if the trace is nil
+func (t *Trace) record(step TraceStep, expr Sqlizer, err error) {
+	if t == nil {
+		return
+	}
+	if expr != nil {
+		sql, args, sqlErr := expr.ToSQL()
+		if sqlErr == nil {
+			step.SQL, step.Args = sql, args
+		}
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.mu.Lock()
+	t.Steps = append(t.Steps, step)
+	t.mu.Unlock()
+}
+
+// finish records the result of the Build
+func (t *Trace) finish(b *SelectBuilder, err error, rendered *renderedQuery) {
+	if t == nil {
+		return
+	}
+	var (
+		sql  string
+		args []interface{}
+	)
+	if err == nil {
+		sql, args, err = rendered.render(b)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.SQL, t.Args = sql, args
+	if err != nil {
+		t.Error = err.Error()
+	}
+}
+
+// functionName returns the name of the function, it is used to name extensions in the trace
+func functionName(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
@@ -0,0 +1,108 @@
+package q2sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/velmie/qparser"
+)
+
+func traceTestExtension(ctx context.Context, query *qparser.Query, builder *SelectBuilder) error {
+	builder.Limit(10)
+	return nil
+}
+
+func TestBuildTrace(t *testing.T) {
+	query, err := qparser.ParseQuery("fields[articles]=id,title&filter[title]=eq:go&sort=-createdAt")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	ctx, trace := WithTrace(context.Background())
+	if _, err = newArticlesBuilder(Extend(traceTestExtension)).Build(ctx, query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := []TraceStep{
+		{
+			Stage:       TraceStageSelect,
+			Parameter:   "fields[articles]",
+			Input:       "id,title",
+			Translation: []string{"id", "title"},
+			Check:       "allowed for selection",
+			SQL:         "id, title",
+		},
+		{
+			Stage:       TraceStageFilter,
+			Parameter:   "filter[title]",
+			Input:       "eq:go",
+			Translation: []string{"title"},
+			Check:       "allowed condition",
+			Condition:   filterEq,
+			SQL:         "title = ?",
+			Args:        []interface{}{"go"},
+		},
+		{
+			Stage:       TraceStageSort,
+			Parameter:   "sort",
+			Input:       "-createdAt",
+			Translation: []string{"created_at"},
+			Check:       "allowed for sorting",
+			SQL:         "created_at DESC",
+		},
+		{
+			Stage:     TraceStageExtension,
+			Extension: "github.com/velmie/q2sql.traceTestExtension",
+		},
+	}
+	if !reflect.DeepEqual(trace.Steps, expected) {
+		t.Errorf("unexpected steps:\n\tgot  %+v\n\twant %+v", trace.Steps, expected)
+	}
+	const expectedSQL = "SELECT id, title FROM articles WHERE title = ? ORDER BY created_at DESC LIMIT 10"
+	if trace.SQL != expectedSQL {
+		t.Errorf("expected sql %q, got %q", expectedSQL, trace.SQL)
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !strings.Contains(string(data), `"parameter":"filter[title]"`) || !strings.Contains(string(data), `"sql":"`+expectedSQL+`"`) {
+		t.Errorf("unexpected json %s", data)
+	}
+}
+
+func TestBuildTraceError(t *testing.T) {
+	query, err := qparser.ParseQuery("filter[title]=like:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	ctx, trace := WithTrace(context.Background())
+	_, err = newArticlesBuilder(Extend(traceTestExtension)).Build(ctx, query)
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) {
+		t.Fatalf("expected *FilterError, got %v", err)
+	}
+	last := trace.Steps[len(trace.Steps)-1]
+	if last.Stage != TraceStageFilter || last.Condition != "like" || last.Error != err.Error() {
+		t.Errorf("unexpected last step %+v", last)
+	}
+	if trace.Error != err.Error() || trace.SQL != "" {
+		t.Errorf("unexpected trace result %q %q", trace.SQL, trace.Error)
+	}
+}
+
+func TestBuildWithoutTrace(t *testing.T) {
+	if TraceFromContext(context.Background()) != nil {
+		t.Error("expected no trace in the context")
+	}
+	query, err := qparser.ParseQuery("filter[title]=eq:go")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, err = newArticlesBuilder(Extend(traceTestExtension)).Build(context.Background(), query); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+}
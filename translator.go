@@ -1,5 +1,13 @@
 package q2sql
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
 // Translator translates given strings from one format to another
 // for example "fieldName" could be translated to "field_name" or "table.field_name"
 type Translator func(in []string) (out []string, err error)
@@ -21,3 +29,184 @@ func MapTranslator(m map[string]string) Translator {
 		return out, nil
 	}
 }
+
+// Case is a naming convention of the identifiers
+type Case string
+
+const (
+	CamelCase  Case = "camel"  // createdAt
+	PascalCase Case = "pascal" // CreatedAt
+	SnakeCase  Case = "snake"  // created_at
+	KebabCase  Case = "kebab"  // created-at
+)
+
+// CaseTranslator creates translator which converts the allowed entries to the given case
+// regardless of their original convention, e.g. "userID" and "user-id" are translated to "user_id" by SnakeCase.
+// The entries which are not in the allow-list are not translated
+func CaseTranslator(to Case, allowed []string) Translator {
+	m := make(map[string]string, len(allowed))
+	for _, entry := range allowed {
+		m[entry] = ConvertCase(entry, to)
+	}
+	return MapTranslator(m)
+}
+
+// ChainTranslators creates translator which translates each entry by the first translator that knows it,
+// so MapTranslator placed in front of CaseTranslator overrides some of the names.
+// Errors other than TranslationError are returned immediately
+func ChainTranslators(translators ...Translator) Translator {
+	return func(in []string) (out []string, err error) {
+		out = make([]string, len(in))
+		for i, entry := range in {
+			out[i], err = translateFirst(translators, entry)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+}
+
+func translateFirst(translators []Translator, entry string) (string, error) {
+	for _, translate := range translators {
+		out, err := translate([]string{entry})
+		var translationErr *TranslationError
+		if errors.As(err, &translationErr) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(out) != 1 {
+			return "", &TranslationError{Entry: entry, Message: "translator returned unexpected number of entries"}
+		}
+		return out[0], nil
+	}
+	return "", &TranslationError{Entry: entry, Message: "translation is not found"}
+}
+
+// TableTranslator prefixes the translated entries with the table name, e.g. "articles.created_at",
+// in order to avoid ambiguous columns in the joined queries.
+// The entries which are already qualified are left as is.
+// The result is a new slice, the one returned by the wrapped translator is not modified
+func TableTranslator(table string, t Translator) Translator {
+	return func(in []string) ([]string, error) {
+		translated, err := t(in)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(translated))
+		for i, entry := range translated {
+			if !strings.Contains(entry, ".") {
+				entry = table + "." + entry
+			}
+			out[i] = entry
+		}
+		return out, nil
+	}
+}
+
+// isPluralSuffix reports whether the rest of the word is plural "s" of the acronym e.g. "IDs"
+func isPluralSuffix(rest []rune) bool {
+	return rest[0] == 's' && (len(rest) == 1 || !unicode.IsLower(rest[1]))
+}
+
+// ConvertCase converts the identifier to the given case.
+// A run of upper case letters is treated as an acronym: "userID" is "user_id", "HTTPStatus" is "http_status",
+// "userIDs" is "user_ids"
+func ConvertCase(s string, to Case) string {
+	words := splitWords(s)
+	var b strings.Builder
+	b.Grow(len(s) + len(words))
+	for i, word := range words {
+		switch to {
+		case SnakeCase, KebabCase:
+			if i > 0 {
+				b.WriteByte(caseSeparator(to))
+			}
+			b.WriteString(strings.ToLower(word))
+		case CamelCase, PascalCase:
+			if i == 0 && to == CamelCase {
+				b.WriteString(strings.ToLower(word))
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(word)
+			b.WriteRune(unicode.ToUpper(r))
+			b.WriteString(strings.ToLower(word[size:]))
+		default:
+			b.WriteString(word)
+		}
+	}
+	return b.String()
+}
+
+func caseSeparator(c Case) byte {
+	if c == KebabCase {
+		return '-'
+	}
+	return '_'
+}
+
+// splitWords splits the identifier into words by the separators and by the case changes
+func splitWords(s string) []string {
+	runes := []rune(s)
+	words := make([]string, 0, 4)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+	}
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			flush(i)
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !isPluralSuffix(runes[i+1:])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+	return words
+}
+
+// BidirectionalTranslator translates API names to the columns and the columns back to API names,
+// e.g. in order to build the response from the scanned rows.
+// Its Translate method is Translator
+//
+//	t, err := q2sql.NewBidirectionalTranslator(map[string]string{"createdAt": "created_at"})
+//	builder := q2sql.NewResourceSelectBuilder("articles", t.Translate, q2sql.WithReverseTranslator(t.Reverse))
+type BidirectionalTranslator struct {
+	forward Translator
+	reverse Translator
+}
+
+// NewBidirectionalTranslator creates BidirectionalTranslator based on the passed map,
+// an error is returned if several names are translated to the same column
+func NewBidirectionalTranslator(m map[string]string) (*BidirectionalTranslator, error) {
+	reverse := make(map[string]string, len(m))
+	for name, column := range m {
+		if other, ok := reverse[column]; ok {
+			return nil, fmt.Errorf("names %q and %q are both translated to %q", other, name, column)
+		}
+		reverse[column] = name
+	}
+	return &BidirectionalTranslator{forward: MapTranslator(m), reverse: MapTranslator(reverse)}, nil
+}
+
+// Translate translates API names to the columns
+func (t *BidirectionalTranslator) Translate(in []string) ([]string, error) {
+	return t.forward(in)
+}
+
+// Reverse translates the columns to API names
+func (t *BidirectionalTranslator) Reverse(in []string) ([]string, error) {
+	return t.reverse(in)
+}
@@ -1,8 +1,12 @@
 package q2sql
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/velmie/qparser"
 )
 
 type mapTranslatorTest struct {
@@ -54,3 +58,275 @@ func TestMapTranslator(t *testing.T) {
 		}
 	}
 }
+
+func TestMapTranslatorResultNotShared(t *testing.T) {
+	m := map[string]string{"title": "title"}
+	translate := MapTranslator(m)
+	out, err := translate([]string{"title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out[0] = "password"
+	m["body"] = "body"
+	out, err = translate([]string{"title", "body"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []string{"title", "body"}; !reflect.DeepEqual(out, want) {
+		t.Errorf("got %v, want %v", out, want)
+	}
+}
+
+func TestConvertCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		to   Case
+		want string
+	}{
+		{in: "createdAt", to: SnakeCase, want: "created_at"},
+		{in: "userID", to: SnakeCase, want: "user_id"},
+		{in: "HTTPStatus", to: SnakeCase, want: "http_status"},
+		{in: "userIDs", to: SnakeCase, want: "user_ids"},
+		{in: "address2Line", to: SnakeCase, want: "address2_line"},
+		{in: "id", to: SnakeCase, want: "id"},
+		{in: "created_at", to: CamelCase, want: "createdAt"},
+		{in: "user-id", to: CamelCase, want: "userId"},
+		{in: "created_at", to: PascalCase, want: "CreatedAt"},
+		{in: "createdAt", to: KebabCase, want: "created-at"},
+		{in: "ProfileURL", to: KebabCase, want: "profile-url"},
+		{in: "", to: SnakeCase, want: ""},
+	}
+	for _, tt := range tests {
+		if got := ConvertCase(tt.in, tt.to); got != tt.want {
+			t.Errorf("ConvertCase(%q, %q) = %q, want %q", tt.in, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCaseTranslator(t *testing.T) {
+	translate := CaseTranslator(SnakeCase, []string{"id", "createdAt", "authorID"})
+	got, err := translate([]string{"createdAt", "authorID", "id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"created_at", "author_id", "id"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	_, err = translate([]string{"password"})
+	var translationErr *TranslationError
+	if !errors.As(err, &translationErr) || translationErr.Entry != "password" {
+		t.Errorf("expected translation error for not allowed entry, got %v", err)
+	}
+}
+
+func TestChainTranslators(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		name        string
+		translators []Translator
+		in          []string
+		out         []string
+		err         error
+	}{
+		{
+			name: "override",
+			translators: []Translator{
+				MapTranslator(map[string]string{"author": "author_name"}),
+				CaseTranslator(SnakeCase, []string{"author", "createdAt"}),
+			},
+			in:  []string{"createdAt", "author"},
+			out: []string{"created_at", "author_name"},
+		},
+		{
+			name: "not found",
+			translators: []Translator{
+				MapTranslator(map[string]string{"author": "author_name"}),
+				CaseTranslator(SnakeCase, []string{"createdAt"}),
+			},
+			in:  []string{"title"},
+			err: &TranslationError{Entry: "title", Message: "translation is not found"},
+		},
+		{
+			name: "other errors are not skipped",
+			translators: []Translator{
+				func([]string) ([]string, error) { return nil, failure },
+				CaseTranslator(SnakeCase, []string{"createdAt"}),
+			},
+			in:  []string{"createdAt"},
+			err: failure,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChainTranslators(tt.translators...)(tt.in)
+			if tt.err != nil {
+				if err == nil || err.Error() != tt.err.Error() {
+					t.Errorf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.out) {
+				t.Errorf("got %v, want %v", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestTableTranslator(t *testing.T) {
+	translate := TableTranslator("articles", ChainTranslators(
+		MapTranslator(map[string]string{"author": "users.name"}),
+		CaseTranslator(SnakeCase, []string{"id", "createdAt"}),
+	))
+	got, err := translate([]string{"id", "createdAt", "author"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"articles.id", "articles.created_at", "users.name"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err = translate([]string{"title"}); err == nil {
+		t.Error("expected error for unknown entry")
+	}
+}
+
+func TestTableTranslatorKeepsInnerResult(t *testing.T) {
+	inner := []string{"id", "users.name"}
+	translate := TableTranslator("articles", func([]string) ([]string, error) {
+		return inner, nil
+	})
+	for i := 0; i < 2; i++ {
+		got, err := translate([]string{"id", "author"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want := []string{"articles.id", "users.name"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if want := []string{"id", "users.name"}; !reflect.DeepEqual(inner, want) {
+		t.Errorf("inner result must not be modified, got %v", inner)
+	}
+}
+
+func TestBidirectionalTranslator(t *testing.T) {
+	translator, err := NewBidirectionalTranslator(map[string]string{"createdAt": "created_at", "authorID": "author_id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var translate Translator = translator.Translate
+	columns, err := translate([]string{"createdAt", "authorID"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []string{"created_at", "author_id"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("got %v, want %v", columns, want)
+	}
+	names, err := translator.Reverse(columns)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []string{"createdAt", "authorID"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	var translationErr *TranslationError
+	if _, err = translator.Reverse([]string{"createdAt"}); !errors.As(err, &translationErr) {
+		t.Errorf("expected translation error for unknown column, got %v", err)
+	}
+
+	_, err = NewBidirectionalTranslator(map[string]string{"author": "author_id", "authorID": "author_id"})
+	if err == nil {
+		t.Error("expected error for ambiguous reverse translation")
+	}
+}
+
+func TestReverseTranslatorInErrors(t *testing.T) {
+	translator, err := NewBidirectionalTranslator(map[string]string{"id": "id", "passwordHash": "password_hash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		name    string
+		query   string
+		options []ResourceSelectBuilderOption
+		want    string
+	}{
+		{
+			name:  "query token",
+			query: "fields[users]=id,passwordHash",
+			want:  `field "passwordHash" not allowed for selection criteria`,
+		},
+		{
+			name:    "reverse translator",
+			query:   "fields[users]=id,passwordHash",
+			options: []ResourceSelectBuilderOption{WithReverseTranslator(translator.Reverse)},
+			want:    `field "passwordHash" not allowed for selection criteria`,
+		},
+		{
+			name:  "always selected column",
+			query: "fields[users]=id",
+			options: []ResourceSelectBuilderOption{
+				WithBidirectionalTranslator(translator),
+				AlwaysSelectFields([]string{"password_hash"}),
+			},
+			want: `field "passwordHash" not allowed for selection criteria`,
+		},
+		{
+			name:    "always selected column without reverse translator",
+			query:   "fields[users]=id",
+			options: []ResourceSelectBuilderOption{AlwaysSelectFields([]string{"password_hash"})},
+			want:    `field "password_hash" not allowed for selection criteria`,
+		},
+	}
+	for _, tt := range tests {
+		query, parseErr := qparser.ParseQuery(tt.query)
+		if parseErr != nil {
+			t.Fatal(parseErr)
+		}
+		builder := NewResourceSelectBuilder(
+			"users",
+			translator.Translate,
+			append([]ResourceSelectBuilderOption{AllowSelectFields([]string{"id"})}, tt.options...)...,
+		)
+		_, err = builder.Build(context.Background(), query)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestWithBidirectionalTranslator(t *testing.T) {
+	translator, err := NewBidirectionalTranslator(map[string]string{"id": "id", "createdAt": "created_at"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	builder := NewResourceSelectBuilder(
+		"users",
+		nil,
+		WithBidirectionalTranslator(translator),
+		AllowSelectFields([]string{"id", "created_at"}),
+	)
+	query, err := qparser.ParseQuery("fields[users]=id,createdAt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := builder.Build(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sql, _, err := b.ToSQL()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := "SELECT id, created_at FROM users"; sql != want {
+		t.Errorf("expected %q, got %q", want, sql)
+	}
+	if names := builder.Describe().Fields; len(names) != 2 || names[0].Name != "createdAt" || names[1].Name != "id" {
+		t.Errorf("expected the fields to be described by API names, got %+v", names)
+	}
+}